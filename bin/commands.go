@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/profitbricks/docker-machine-driver-profitbricks"
+)
+
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"resize":           {"Change the cores, RAM and CPU family of a machine", resize},
+	"grow-disk":        {"Grow the boot volume and root filesystem, or a data volume, of a machine", growDisk},
+	"snapshot":         {"Create, list, restore and prune boot volume snapshots of a machine", snapshot},
+	"bake":             {"Bake a golden snapshot from a provisioned machine", bake},
+	"console-password": {"Print the generated console password of a machine", consolePassword},
+	"locations":        {"List locations with their features and image aliases", locations},
+	"images":           {"List images, filtered by location and type", images},
+	"snapshots":        {"List snapshots, filtered by location", snapshots},
+	"cpu-families":     {"List the CPU families of each location", cpuFamilies},
+	"plan":             {"Print the API requests creating a machine would submit", plan},
+	"estimate":         {"Estimate the cost of a machine configuration from a price table", estimate},
+}
+
+func run(name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		printUsage()
+		if name == "help" || name == "-h" || name == "--help" {
+			return nil
+		}
+		return fmt.Errorf("Unknown command %q", name)
+	}
+	return cmd.run(args)
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s COMMAND [options]\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-17s %s\n", name, commands[name].description)
+	}
+}
+
+// newFlagSet returns the flag set of a machine command, with the store path option.
+func newFlagSet(name, usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s\n", os.Args[0], usage)
+		fs.PrintDefaults()
+	}
+	storePath := fs.String("storage-path", profitbricks.DefaultStorePath(), "docker-machine store path [$MACHINE_STORAGE_PATH]")
+	return fs, storePath
+}
+
+// loadMachine parses the command line, which must hold a machine name followed by
+// nargs arguments, and loads the machine it names.
+func loadMachine(fs *flag.FlagSet, storePath *string, args []string, nargs int) (*profitbricks.Driver, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != nargs+1 {
+		fs.Usage()
+		if nargs == 0 {
+			return nil, errors.New("Expected a machine name")
+		}
+		return nil, fmt.Errorf("Expected a machine name and %d more arguments", nargs)
+	}
+	return profitbricks.LoadDriver(*storePath, fs.Arg(0))
+}
+
+func resize(args []string) error {
+	fs, storePath := newFlagSet("resize", "resize [--cores N] [--ram MB] [--cpu-family FAMILY] MACHINE")
+	cores := fs.Int("cores", 0, "number of cores, 0 keeps the current value")
+	ram := fs.Int("ram", 0, "RAM in MB, 0 keeps the current value")
+	cpuFamily := fs.String("cpu-family", "", "CPU family (AMD_OPTERON, INTEL_XEON), empty keeps the current value")
+
+	d, err := loadMachine(fs, storePath, args, 0)
+	if err != nil {
+		return err
+	}
+	if err := d.Resize(*cores, *ram, *cpuFamily); err != nil {
+		return err
+	}
+	return d.SaveConfig()
+}
+
+func growDisk(args []string) error {
+	fs, storePath := newFlagSet("grow-disk", "grow-disk --size GB [--volume NAME] MACHINE")
+	size := fs.Int("size", 0, "new volume size in GB")
+	volume := fs.String("volume", "", "name of a data volume created by docker-machine, defaults to the boot volume")
+
+	d, err := loadMachine(fs, storePath, args, 0)
+	if err != nil {
+		return err
+	}
+	if *size <= 0 {
+		return errors.New("--size is required")
+	}
+
+	err = d.GrowDisk(*volume, *size)
+	if saveErr := d.SaveConfig(); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+func snapshot(args []string) error {
+	if len(args) == 0 {
+		return errors.New("Usage: snapshot create|list|restore|prune [options] MACHINE")
+	}
+
+	switch args[0] {
+	case "create":
+		fs, storePath := newFlagSet("snapshot create", "snapshot create [--keep N] MACHINE")
+		keep := fs.Int("keep", 0, "prune all but the newest N snapshots afterwards, 0 keeps all")
+		d, err := loadMachine(fs, storePath, args[1:], 0)
+		if err != nil {
+			return err
+		}
+		snapshot, err := d.CreateSnapshot()
+		if err != nil {
+			return err
+		}
+		fmt.Println(snapshot.Id)
+		if *keep > 0 {
+			_, err = d.PruneSnapshots(*keep)
+		}
+		return err
+	case "list":
+		fs, storePath := newFlagSet("snapshot list", "snapshot list MACHINE")
+		d, err := loadMachine(fs, storePath, args[1:], 0)
+		if err != nil {
+			return err
+		}
+		snapshots, err := d.ListSnapshots()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tID\tSIZE\tCREATED")
+		for _, s := range snapshots {
+			fmt.Fprintf(w, "%s\t%s\t%d GB\t%s\n", s.Name, s.Id, s.Size, s.Created.Format(time.RFC3339))
+		}
+		return w.Flush()
+	case "restore":
+		fs, storePath := newFlagSet("snapshot restore", "snapshot restore MACHINE SNAPSHOT")
+		d, err := loadMachine(fs, storePath, args[1:], 1)
+		if err != nil {
+			return err
+		}
+		return d.RestoreSnapshot(fs.Arg(1))
+	case "prune":
+		fs, storePath := newFlagSet("snapshot prune", "snapshot prune --keep N MACHINE")
+		keep := fs.Int("keep", -1, "number of newest snapshots to keep")
+		d, err := loadMachine(fs, storePath, args[1:], 0)
+		if err != nil {
+			return err
+		}
+		if *keep < 0 {
+			return errors.New("--keep is required")
+		}
+		_, err = d.PruneSnapshots(*keep)
+		return err
+	}
+	return fmt.Errorf("Unknown snapshot command %q", args[0])
+}
+
+func bake(args []string) error {
+	fs, storePath := newFlagSet("bake", "bake [--name NAME] [--remove] MACHINE")
+	name := fs.String("name", "", "snapshot name, defaults to MACHINE-golden-TIMESTAMP")
+	remove := fs.Bool("remove", false, "remove the machine once its snapshot is baked")
+
+	d, err := loadMachine(fs, storePath, args, 0)
+	if err != nil {
+		return err
+	}
+	golden, err := d.Bake(*name)
+	if err != nil {
+		return err
+	}
+
+	if *remove {
+		if err := d.Remove(); err != nil {
+			return err
+		}
+		if err := d.RemoveFromStore(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(golden.SnapshotId)
+	fmt.Fprintf(os.Stderr, "Create machines from %s with --profitbricks-image %s --profitbricks-ssh-key-path %s\n", golden.SnapshotName, golden.SnapshotId, golden.KeyPath)
+	return nil
+}
+
+func consolePassword(args []string) error {
+	fs, storePath := newFlagSet("console-password", "console-password MACHINE")
+	d, err := loadMachine(fs, storePath, args, 0)
+	if err != nil {
+		return err
+	}
+	password, err := d.ConsolePassword()
+	if err != nil {
+		return err
+	}
+	fmt.Println(password)
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/profitbricks/docker-machine-driver-profitbricks"
+)
+
+// apiOptions are the credentials and output options of the discovery commands.
+type apiOptions struct {
+	storePath *string
+	endpoint  *string
+	username  *string
+	password  *string
+	refresh   *bool
+	format    *string
+}
+
+// newAPIFlagSet returns the flag set of a discovery command, with the credentials read
+// from the same environment variables as the driver flags.
+func newAPIFlagSet(name, usage string) (*flag.FlagSet, *apiOptions) {
+	fs, storePath := newFlagSet(name, usage)
+	endpoint := os.Getenv("PROFITBRICKS_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "https://api.profitbricks.com/cloudapi/v4"
+	}
+	return fs, &apiOptions{
+		storePath: storePath,
+		endpoint:  fs.String("endpoint", endpoint, "ProfitBricks API endpoint [$PROFITBRICKS_ENDPOINT]"),
+		username:  fs.String("username", os.Getenv("PROFITBRICKS_USERNAME"), "ProfitBricks username [$PROFITBRICKS_USERNAME]"),
+		password:  fs.String("password", os.Getenv("PROFITBRICKS_PASSWORD"), "ProfitBricks password [$PROFITBRICKS_PASSWORD]"),
+		refresh:   fs.Bool("refresh-cache", false, "fetch locations and images from the API and refresh the cache"),
+		format:    fs.String("format", "table", "output format (table, json)"),
+	}
+}
+
+// driver parses the command line and returns a driver for the API credentials.
+func (o *apiOptions) driver(fs *flag.FlagSet, args []string) (*profitbricks.Driver, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return nil, fmt.Errorf("Unexpected arguments %s", strings.Join(fs.Args(), " "))
+	}
+	if *o.format != "table" && *o.format != "json" {
+		return nil, fmt.Errorf("Output format %s is not valid, use table or json", *o.format)
+	}
+	if *o.username == "" {
+		return nil, fmt.Errorf("Please provide username as parameter --username or as environment variable $PROFITBRICKS_USERNAME")
+	}
+
+	d := profitbricks.NewAPIDriver(*o.storePath, *o.endpoint, *o.username, *o.password)
+	d.RefreshCache = *o.refresh
+	return d, nil
+}
+
+// print writes rows as indented JSON, or as a table with the given header.
+func (o *apiOptions) print(rows interface{}, header string, line func(w *tabwriter.Writer)) error {
+	if *o.format == "json" {
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, header)
+	line(w)
+	return w.Flush()
+}
+
+type locationRow struct {
+	Id           string   `json:"id"`
+	Name         string   `json:"name"`
+	Features     []string `json:"features"`
+	ImageAliases []string `json:"imageAliases"`
+}
+
+func locations(args []string) error {
+	fs, opts := newAPIFlagSet("locations", "locations [options]")
+	d, err := opts.driver(fs, args)
+	if err != nil {
+		return err
+	}
+	locations, err := d.Locations()
+	if err != nil {
+		return err
+	}
+
+	rows := make([]locationRow, len(locations))
+	for i, l := range locations {
+		rows[i] = locationRow{l.Id, l.Properties.Name, l.Properties.Features, l.Properties.ImageAliases}
+	}
+	return opts.print(rows, "ID\tNAME\tFEATURES\tIMAGE ALIASES", func(w *tabwriter.Writer) {
+		for _, r := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Id, r.Name, strings.Join(r.Features, ","), strings.Join(r.ImageAliases, ","))
+		}
+	})
+}
+
+type imageRow struct {
+	Name        string `json:"name"`
+	Id          string `json:"id"`
+	Location    string `json:"location"`
+	ImageType   string `json:"imageType"`
+	Size        int    `json:"size"`
+	LicenceType string `json:"licenceType"`
+	Public      bool   `json:"public"`
+}
+
+func images(args []string) error {
+	fs, opts := newAPIFlagSet("images", "images [--location LOCATION] [--type HDD|CDROM] [options]")
+	location := fs.String("location", "", "only list images of this location")
+	imageType := fs.String("type", "", "only list images of this type (HDD, CDROM)")
+	d, err := opts.driver(fs, args)
+	if err != nil {
+		return err
+	}
+	images, err := d.Images(*location, strings.ToUpper(*imageType))
+	if err != nil {
+		return err
+	}
+
+	rows := make([]imageRow, len(images))
+	for i, image := range images {
+		p := image.Properties
+		rows[i] = imageRow{p.Name, image.Id, p.Location, p.ImageType, p.Size, p.LicenceType, p.Public}
+	}
+	return opts.print(rows, "NAME\tID\tLOCATION\tTYPE\tSIZE\tLICENCE\tVISIBILITY", func(w *tabwriter.Writer) {
+		for _, r := range rows {
+			visibility := "private"
+			if r.Public {
+				visibility = "public"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d GB\t%s\t%s\n", r.Name, r.Id, r.Location, r.ImageType, r.Size, r.LicenceType, visibility)
+		}
+	})
+}
+
+type snapshotRow struct {
+	Name     string    `json:"name"`
+	Id       string    `json:"id"`
+	Location string    `json:"location"`
+	Size     int       `json:"size"`
+	Created  time.Time `json:"created"`
+}
+
+func snapshots(args []string) error {
+	fs, opts := newAPIFlagSet("snapshots", "snapshots [--location LOCATION] [options]")
+	location := fs.String("location", "", "only list snapshots of this location")
+	d, err := opts.driver(fs, args)
+	if err != nil {
+		return err
+	}
+	snapshots, err := d.Snapshots(*location)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]snapshotRow, len(snapshots))
+	for i, s := range snapshots {
+		rows[i] = snapshotRow{s.Properties.Name, s.Id, s.Properties.Location, s.Properties.Size, s.Metadata.CreatedDate}
+	}
+	return opts.print(rows, "NAME\tID\tLOCATION\tSIZE\tCREATED", func(w *tabwriter.Writer) {
+		for _, r := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d GB\t%s\n", r.Name, r.Id, r.Location, r.Size, r.Created.Format(time.RFC3339))
+		}
+	})
+}
+
+type cpuFamiliesRow struct {
+	Location    string   `json:"location"`
+	CpuFamilies []string `json:"cpuFamilies"`
+}
+
+func cpuFamilies(args []string) error {
+	fs, opts := newAPIFlagSet("cpu-families", "cpu-families [--location LOCATION] [options]")
+	location := fs.String("location", "", "only list the CPU families of this location")
+	d, err := opts.driver(fs, args)
+	if err != nil {
+		return err
+	}
+	locations, err := d.Locations()
+	if err != nil {
+		return err
+	}
+
+	var rows []cpuFamiliesRow
+	for _, l := range locations {
+		if *location == "" || l.Id == *location {
+			rows = append(rows, cpuFamiliesRow{l.Id, profitbricks.CpuFamilies(l)})
+		}
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("Location %s does not exist", *location)
+	}
+	return opts.print(rows, "LOCATION\tCPU FAMILIES", func(w *tabwriter.Writer) {
+		for _, r := range rows {
+			families := strings.Join(r.CpuFamilies, ",")
+			if families == "" {
+				families = "unknown"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", r.Location, families)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/profitbricks/docker-machine-driver-profitbricks"
+)
+
+func estimate(args []string) error {
+	fs, storePath, opts := newDriverFlagSet("estimate", "estimate [--prices FILE] [--volume TYPE:GB ...] [--extra-ips N] [--profitbricks-* options] [MACHINE]\n       estimate --all [--prices FILE]")
+	prices := fs.String("prices", "", "price table, defaults to profitbricks-prices.json in the store")
+	var volumes stringSlice
+	fs.Var(&volumes, "volume", "extra volume as TYPE:GB, e.g. SSD:100, can be repeated")
+	extraIps := fs.Int("extra-ips", 0, "reserved IPs besides the one of the machine")
+	all := fs.Bool("all", false, "sum the cost of the ProfitBricks machines in the store instead")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 || (*all && fs.NArg() > 0) {
+		fs.Usage()
+		return errors.New("Expected at most a machine name, and none with --all")
+	}
+	if *prices == "" {
+		*prices = profitbricks.DefaultPriceTablePath(*storePath)
+	}
+
+	var machines []*profitbricks.Driver
+	if *all {
+		var err error
+		if machines, err = profitbricks.LoadDrivers(*storePath); err != nil {
+			return err
+		}
+		if len(machines) == 0 {
+			return fmt.Errorf("No ProfitBricks machines in %s", *storePath)
+		}
+	} else {
+		d := profitbricks.NewDriver(fs.Arg(0), *storePath).(*profitbricks.Driver)
+		if err := d.SetConfigFromFlags(opts); err != nil {
+			return err
+		}
+		machines = []*profitbricks.Driver{d}
+	}
+
+	var locations []string
+	for _, d := range machines {
+		locations = append(locations, d.Location)
+	}
+	table, err := profitbricks.LoadPriceTable(*prices, locations)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "MACHINE\tLOCATION\tCORES\tRAM\tHDD\tSSD\tIPS\t%s/HOUR\t%s/MONTH\n", table.Currency, table.Currency)
+	var hourly, monthly float64
+	for _, d := range machines {
+		var e profitbricks.Estimate
+		if *all {
+			e, err = d.EstimateCost(table, nil, 0)
+		} else {
+			e, err = d.EstimateCost(table, volumes.values, *extraIps)
+		}
+		if err != nil {
+			return err
+		}
+		hourly += e.Hourly
+		monthly += e.Monthly
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d MB\t%d GB\t%d GB\t%d\t%.4f\t%.2f\n", e.Machine, e.Location, e.Cores, e.Ram, e.HddSize, e.SsdSize, e.Ips, e.Hourly, e.Monthly)
+	}
+	if len(machines) > 1 {
+		fmt.Fprintf(w, "TOTAL\t\t\t\t\t\t\t%.4f\t%.2f\n", hourly, monthly)
+	}
+	return w.Flush()
+}
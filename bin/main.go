@@ -1,10 +1,22 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/docker/machine/libmachine/drivers/plugin"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/profitbricks/docker-machine-driver-profitbricks"
 )
 
 func main() {
-	plugin.RegisterDriver(profitbricks.NewDriver("", ""))
+	if os.Getenv(localbinary.PluginEnvKey) == localbinary.PluginEnvVal || len(os.Args) < 2 {
+		plugin.RegisterDriver(profitbricks.NewDriver("", ""))
+		return
+	}
+
+	if err := run(os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
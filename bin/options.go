@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/profitbricks/docker-machine-driver-profitbricks"
+)
+
+// driverFlags registers the driver's create flags on a flag set, with their environment
+// variables as defaults like docker-machine does, and serves the parsed values to
+// SetConfigFromFlags. Flags the driver does not declare, like the swarm options, read
+// as zero values.
+type driverFlags struct {
+	strings map[string]*string
+	slices  map[string]*stringSlice
+	ints    map[string]*int
+	bools   map[string]*bool
+}
+
+func addDriverFlags(fs *flag.FlagSet, flags []mcnflag.Flag) *driverFlags {
+	f := &driverFlags{
+		strings: map[string]*string{},
+		slices:  map[string]*stringSlice{},
+		ints:    map[string]*int{},
+		bools:   map[string]*bool{},
+	}
+	for _, fl := range flags {
+		switch fl := fl.(type) {
+		case mcnflag.StringFlag:
+			value := fl.Value
+			if env := os.Getenv(fl.EnvVar); fl.EnvVar != "" && env != "" {
+				value = env
+			}
+			f.strings[fl.Name] = fs.String(fl.Name, value, usage(fl.Usage, fl.EnvVar))
+		case mcnflag.StringSliceFlag:
+			value := &stringSlice{values: fl.Value, isDefault: true}
+			if env := os.Getenv(fl.EnvVar); fl.EnvVar != "" && env != "" {
+				value.values = strings.Split(env, ",")
+			}
+			fs.Var(value, fl.Name, usage(fl.Usage, fl.EnvVar))
+			f.slices[fl.Name] = value
+		case mcnflag.IntFlag:
+			value := fl.Value
+			if env, err := strconv.Atoi(os.Getenv(fl.EnvVar)); fl.EnvVar != "" && err == nil {
+				value = env
+			}
+			f.ints[fl.Name] = fs.Int(fl.Name, value, usage(fl.Usage, fl.EnvVar))
+		case mcnflag.BoolFlag:
+			value, _ := strconv.ParseBool(os.Getenv(fl.EnvVar))
+			f.bools[fl.Name] = fs.Bool(fl.Name, value, usage(fl.Usage, fl.EnvVar))
+		}
+	}
+	return f
+}
+
+func usage(text, envVar string) string {
+	if envVar == "" {
+		return text
+	}
+	return fmt.Sprintf("%s [$%s]", text, envVar)
+}
+
+func (f *driverFlags) String(key string) string {
+	if value, ok := f.strings[key]; ok {
+		return *value
+	}
+	return ""
+}
+
+func (f *driverFlags) StringSlice(key string) []string {
+	if value, ok := f.slices[key]; ok {
+		return value.values
+	}
+	return nil
+}
+
+func (f *driverFlags) Int(key string) int {
+	if value, ok := f.ints[key]; ok {
+		return *value
+	}
+	return 0
+}
+
+func (f *driverFlags) Bool(key string) bool {
+	if value, ok := f.bools[key]; ok {
+		return *value
+	}
+	return false
+}
+
+// stringSlice is a repeatable flag, replacing its default when first set.
+type stringSlice struct {
+	values    []string
+	isDefault bool
+}
+
+func (s *stringSlice) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s.values, ",")
+}
+
+func (s *stringSlice) Set(value string) error {
+	if s.isDefault {
+		s.values, s.isDefault = nil, false
+	}
+	s.values = append(s.values, value)
+	return nil
+}
+
+// newDriverFlagSet returns the flag set of a command taking the driver's create
+// options.
+func newDriverFlagSet(name, usage string) (*flag.FlagSet, *string, *driverFlags) {
+	fs, storePath := newFlagSet(name, usage)
+	opts := addDriverFlags(fs, profitbricks.NewDriver("", "").GetCreateFlags())
+	return fs, storePath, opts
+}
+
+// configureDriver parses the command line, which must hold a machine name, and returns
+// a driver for the new machine configured from the create options.
+func configureDriver(fs *flag.FlagSet, storePath *string, opts *driverFlags, args []string) (*profitbricks.Driver, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, errors.New("Expected a machine name")
+	}
+
+	d := profitbricks.NewDriver(fs.Arg(0), *storePath).(*profitbricks.Driver)
+	if err := d.SetConfigFromFlags(opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func plan(args []string) error {
+	fs, storePath, opts := newDriverFlagSet("plan", "plan [--profitbricks-* options] MACHINE")
+	d, err := configureDriver(fs, storePath, opts, args)
+	if err != nil {
+		return err
+	}
+	d.DryRun = false
+
+	if err := d.PreCreateCheck(); err != nil {
+		return err
+	}
+	steps, err := d.Plan()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(steps, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
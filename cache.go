@@ -0,0 +1,148 @@
+package profitbricks
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+const defaultCacheTTL = 3600
+
+// cacheEntry is a cached API listing with the time it was fetched.
+type cacheEntry struct {
+	Fetched time.Time       `json:"fetched"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// cachePath returns the file of a cached listing. Listings are shared by all machines
+// of the store using the same endpoint and user.
+func (d *Driver) cachePath(name string) string {
+	key := sha256.Sum256([]byte(d.URL + "\x00" + d.Username))
+	return filepath.Join(d.StorePath, "cache", "profitbricks", fmt.Sprintf("%x-%s.json", key[:8], name))
+}
+
+// readCache loads a cached listing into v, reporting whether it was found and fresh.
+func (d *Driver) readCache(name string, v interface{}) bool {
+	if d.CacheTTL <= 0 || d.RefreshCache || d.StorePath == "" {
+		return false
+	}
+
+	data, err := ioutil.ReadFile(d.cachePath(name))
+	if err != nil {
+		return false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.Debugf("Ignoring unreadable %s cache: %s", name, err)
+		return false
+	}
+	if time.Since(entry.Fetched) > time.Duration(d.CacheTTL)*time.Second {
+		return false
+	}
+	if err := json.Unmarshal(entry.Data, v); err != nil {
+		log.Debugf("Ignoring unreadable %s cache: %s", name, err)
+		return false
+	}
+	log.Debugf("Using %s cached at %s", name, entry.Fetched.Format(time.RFC3339))
+	return true
+}
+
+// writeCache stores a listing, logging failures since the cache is only an optimisation.
+func (d *Driver) writeCache(name string, v interface{}) {
+	if d.CacheTTL <= 0 || d.StorePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Debugf("Error caching %s: %s", name, err)
+		return
+	}
+	data, err = json.Marshal(cacheEntry{Fetched: time.Now(), Data: data})
+	if err != nil {
+		log.Debugf("Error caching %s: %s", name, err)
+		return
+	}
+
+	path := d.cachePath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		log.Debugf("Error caching %s: %s", name, err)
+		return
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Debugf("Error caching %s: %s", name, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Debugf("Error caching %s: %s", name, err)
+	}
+}
+
+// listLocations returns all locations with their features and image aliases.
+func (d *Driver) listLocations() ([]profitbricks.Location, error) {
+	if d.locations != nil {
+		return d.locations, nil
+	}
+
+	var locations []profitbricks.Location
+	if !d.readCache("locations", &locations) {
+		d.setPB()
+		resp := profitbricks.ListLocations()
+		if resp.StatusCode == 401 {
+			return nil, errors.New("Authentication failed")
+		}
+		if resp.StatusCode > 299 {
+			return nil, fmt.Errorf("Error occurred while listing locations: %s", resp.Response)
+		}
+		locations = resp.Items
+		d.writeCache("locations", locations)
+	}
+	d.locations = locations
+	return locations, nil
+}
+
+// findLocation returns the location with the given ID.
+func (d *Driver) findLocation(id string) (*profitbricks.Location, error) {
+	locations, err := d.listLocations()
+	if err != nil {
+		return nil, err
+	}
+	for i := range locations {
+		if locations[i].Id == id {
+			return &locations[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Location %s does not exist", id)
+}
+
+// listImages returns the image catalog of all locations.
+func (d *Driver) listImages() ([]profitbricks.Image, error) {
+	if d.images != nil {
+		return d.images, nil
+	}
+
+	var images []profitbricks.Image
+	if !d.readCache("images", &images) {
+		d.setPB()
+		resp := profitbricks.ListImages()
+		if resp.StatusCode == 401 {
+			return nil, errors.New("Authentication failed")
+		}
+		if resp.StatusCode > 299 {
+			return nil, fmt.Errorf("Error occurred while listing images: %s", resp.Response)
+		}
+		images = resp.Items
+		d.writeCache("images", images)
+	}
+	d.images = images
+	return images, nil
+}
@@ -0,0 +1,58 @@
+package profitbricks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+func TestCache(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "machine-store-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+
+	d := &Driver{
+		URL:        "https://api.profitbricks.com/cloudapi/v4",
+		Username:   "user",
+		CacheTTL:   60,
+		BaseDriver: &drivers.BaseDriver{StorePath: storePath},
+	}
+	d.writeCache("names", []string{"a", "b"})
+
+	var names []string
+	if !d.readCache("names", &names) || len(names) != 2 {
+		t.Fatalf("Expected the cached names, got %v", names)
+	}
+
+	d.RefreshCache = true
+	if d.readCache("names", &names) {
+		t.Error("Expected the cache to be bypassed when refreshing")
+	}
+	d.RefreshCache = false
+
+	other := *d
+	other.Username = "other"
+	if other.readCache("names", &names) {
+		t.Error("Expected the cache of another user to be separate")
+	}
+
+	data, _ := json.Marshal(cacheEntry{Fetched: time.Now().Add(-2 * time.Minute), Data: json.RawMessage(`["a"]`)})
+	if err := ioutil.WriteFile(d.cachePath("names"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if d.readCache("names", &names) {
+		t.Error("Expected an expired cache to be ignored")
+	}
+
+	d.CacheTTL = 0
+	d.writeCache("disabled", []string{"a"})
+	if _, err := os.Stat(d.cachePath("disabled")); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be cached with a TTL of 0")
+	}
+}
@@ -0,0 +1,76 @@
+package profitbricks
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// defaultCdromLicenceType is the licence type of the empty volume of machines booted
+// from a CD-ROM, which the API cannot take from an image.
+const defaultCdromLicenceType = "LINUX"
+
+// cdromVolumes returns the empty volume attached to machines booted from a CD-ROM.
+func (d *Driver) cdromVolumes() []profitbricks.Volume {
+	licenceType := d.LicenceType
+	if licenceType == "" {
+		licenceType = defaultCdromLicenceType
+	}
+
+	volume := profitbricks.Volume{
+		Properties: profitbricks.VolumeProperties{
+			Type:             d.DiskType,
+			Size:             d.DiskSize,
+			Name:             d.MachineName,
+			AvailabilityZone: d.VolumeAvailabilityZone,
+			Bus:              d.VolumeBus,
+			LicenceType:      licenceType,
+		},
+	}
+	setHotPlug(&volume.Properties, d.HotPlug)
+	return []profitbricks.Volume{volume}
+}
+
+func (d *Driver) bootCdromReference() *profitbricks.ResourceReference {
+	if !d.BootCdrom || d.ImageId == "" {
+		return nil
+	}
+	return &profitbricks.ResourceReference{Id: d.ImageId}
+}
+
+// attachBootCdrom makes sure the CD-ROM the server boots from is attached, attaching
+// it and rebooting from it when the API did not do so on creation.
+func (d *Driver) attachBootCdrom() error {
+	if d.ImageId == "" {
+		return fmt.Errorf("CD-ROM image %s was not resolved", d.Image)
+	}
+
+	cdroms := profitbricks.ListAttachedCdroms(d.DatacenterId, d.ServerId)
+	if cdroms.StatusCode > 299 {
+		return fmt.Errorf("Error occurred while listing CD-ROMs: %s", cdroms.Response)
+	}
+	for _, cdrom := range cdroms.Items {
+		if cdrom.Id == d.ImageId {
+			return nil
+		}
+	}
+
+	log.Infof("Attaching CD-ROM %s", d.ImageName)
+	cdrom := profitbricks.AttachCdrom(d.DatacenterId, d.ServerId, d.ImageId)
+	if cdrom.StatusCode > 299 {
+		return fmt.Errorf("Error while attaching a CD-ROM %s", cdrom.Response)
+	}
+	if err := d.waitTillProvisioned(cdrom.Headers.Get("Location")); err != nil {
+		return err
+	}
+	if err := d.patchServer(profitbricks.ServerProperties{BootCdrom: d.bootCdromReference()}); err != nil {
+		return err
+	}
+
+	resp := profitbricks.RebootServer(d.DatacenterId, d.ServerId)
+	if resp.StatusCode != 202 {
+		return fmt.Errorf("Error while rebooting from the CD-ROM %s", resp.Body)
+	}
+	return d.waitTillProvisioned(resp.Headers.Get("Location"))
+}
@@ -0,0 +1,181 @@
+package profitbricks
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// rotateIdentityCommand replaces the authorized key the clone was created with and
+// clears the SSH host keys and Docker identity copied from the source machine.
+// Provisioning generates new Docker TLS certificates afterwards.
+const rotateIdentityCommand = sudoPreamble + `umask 077
+mkdir -p ~/.ssh
+touch ~/.ssh/authorized_keys
+{ grep -vxF %s ~/.ssh/authorized_keys || true; echo %s; } > ~/.ssh/authorized_keys.new
+mv ~/.ssh/authorized_keys.new ~/.ssh/authorized_keys
+$SUDO rm -f /etc/ssh/ssh_host_*
+$SUDO ssh-keygen -A
+$SUDO systemctl restart ssh 2>/dev/null || $SUDO systemctl restart sshd 2>/dev/null || $SUDO service ssh restart
+$SUDO rm -f /etc/docker/key.json /etc/docker/ca.pem /etc/docker/server.pem /etc/docker/server-key.pem`
+
+// prepareClone loads the machine to clone, and copies its location and size to the
+// driver.
+func (d *Driver) prepareClone() (*Driver, profitbricks.Server, error) {
+	source, err := LoadDriver(d.StorePath, d.CloneFrom)
+	if err != nil {
+		return nil, profitbricks.Server{}, err
+	}
+
+	d.setPB()
+	server := profitbricks.GetServer(source.DatacenterId, source.ServerId)
+	if server.StatusCode > 299 {
+		return nil, server, fmt.Errorf("Error occurred while fetching the server of machine %s: %s", source.MachineName, server.Response)
+	}
+
+	d.Location = source.Location
+	d.SSHUser = source.SSHUser
+	d.ServerAvailabilityZone = source.ServerAvailabilityZone
+	d.Cores = server.Properties.Cores
+	d.Ram = server.Properties.Ram
+	d.CpuFamily = server.Properties.CpuFamily
+	if volume := bootVolume(server); volume != nil {
+		d.DiskSize = volume.Properties.Size
+		d.DiskType = volume.Properties.Type
+		d.VolumeBus = volume.Properties.Bus
+	}
+	log.Infof("Cloning machine %s in %s", source.MachineName, d.Location)
+	return source, server, nil
+}
+
+// snapshotCloneSource snapshots every volume of the machine to clone, boot volume
+// first, and returns the volumes to create from the snapshots. The snapshot IDs are
+// returned even on failure so they can be deleted.
+func (d *Driver) snapshotCloneSource() ([]profitbricks.Volume, []string, error) {
+	source, server, err := d.prepareClone()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if d.SSHKey == "" {
+		if d.SSHKey, err = d.copySSHKey(source.GetSSHKeyPath()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sourceVolumes := cloneSourceVolumes(server)
+	if len(sourceVolumes) == 0 {
+		return nil, nil, fmt.Errorf("Server of machine %s has no volumes", source.MachineName)
+	}
+
+	timestamp := time.Now().UTC().Format(snapshotTimeFormat)
+	var volumes []profitbricks.Volume
+	var snapshotIds []string
+	for i, volume := range sourceVolumes {
+		name := fmt.Sprintf("%s-clone-%d-%s", d.MachineName, i, timestamp)
+		log.Infof("Creating snapshot %s of volume %s", name, volume.Properties.Name)
+		snapshot := profitbricks.CreateSnapshot(source.DatacenterId, volume.Id, name, "docker-machine clone of "+source.MachineName)
+		if snapshot.StatusCode > 299 {
+			return nil, snapshotIds, errors.New("Error while creating a snapshot " + snapshot.Response)
+		}
+		snapshotIds = append(snapshotIds, snapshot.Id)
+		if err := d.waitTillProvisioned(snapshot.Headers.Get("Location")); err != nil {
+			return nil, snapshotIds, err
+		}
+		volumes = append(volumes, d.cloneVolume(i, volume, snapshot.Id))
+	}
+	return volumes, snapshotIds, nil
+}
+
+// shellQuote quotes s as a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// cloneSourceVolumes returns the volumes of the server to clone, boot volume first.
+func cloneSourceVolumes(server profitbricks.Server) []profitbricks.Volume {
+	boot := bootVolume(server)
+	if boot == nil {
+		return nil
+	}
+	volumes := []profitbricks.Volume{*boot}
+	for _, volume := range server.Entities.Volumes.Items {
+		if volume.Id != boot.Id {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes
+}
+
+// cloneVolume returns the i-th volume of the clone, created from a snapshot of the
+// source volume.
+func (d *Driver) cloneVolume(i int, source profitbricks.Volume, snapshotId string) profitbricks.Volume {
+	name := d.MachineName
+	if i > 0 {
+		name = fmt.Sprintf("%s-%d", d.MachineName, i)
+	}
+	return profitbricks.Volume{
+		Properties: profitbricks.VolumeProperties{
+			Name:             name,
+			Type:             source.Properties.Type,
+			Size:             source.Properties.Size,
+			Image:            snapshotId,
+			Bus:              source.Properties.Bus,
+			AvailabilityZone: d.VolumeAvailabilityZone,
+		},
+	}
+}
+
+// deleteSnapshots deletes temporary snapshots, logging failures.
+func (d *Driver) deleteSnapshots(snapshotIds []string) {
+	for _, id := range snapshotIds {
+		resp := profitbricks.DeleteSnapshot(id)
+		if resp.StatusCode > 299 {
+			log.Warnf("Error deleting snapshot %s: %s", id, resp.Body)
+			continue
+		}
+		if err := d.waitTillProvisioned(resp.Headers.Get("Location")); err != nil {
+			log.Warnf("Error deleting snapshot %s: %s", id, err)
+		}
+	}
+}
+
+// rotateIdentity replaces the SSH key copied from the source machine with a new one,
+// and clears the host keys and Docker identity of the clone.
+func (d *Driver) rotateIdentity() error {
+	if err := drivers.WaitForSSH(d); err != nil {
+		return err
+	}
+
+	keyPath := d.GetSSHKeyPath() + ".new"
+	if err := ssh.GenerateSSHKey(keyPath); err != nil {
+		return err
+	}
+	publicKey, err := ioutil.ReadFile(keyPath + ".pub")
+	if err != nil {
+		return err
+	}
+
+	log.Info("Regenerating the SSH keys of the clone")
+	command := fmt.Sprintf(rotateIdentityCommand, shellQuote(strings.TrimSpace(d.SSHKey)), shellQuote(strings.TrimSpace(string(publicKey))))
+	if _, err := runSSHCommand(d, command); err != nil {
+		return err
+	}
+
+	if err := os.Rename(keyPath, d.GetSSHKeyPath()); err != nil {
+		return err
+	}
+	if err := os.Rename(keyPath+".pub", d.publicSSHKeyPath()); err != nil {
+		return err
+	}
+	d.SSHKey = string(publicKey)
+	return nil
+}
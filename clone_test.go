@@ -0,0 +1,107 @@
+package profitbricks
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+func TestCloneSourceVolumes(t *testing.T) {
+	volumes := func(ids ...string) *profitbricks.Volumes {
+		items := make([]profitbricks.Volume, len(ids))
+		for i, id := range ids {
+			items[i].Id = id
+		}
+		return &profitbricks.Volumes{Items: items}
+	}
+
+	tests := []struct {
+		volumes  *profitbricks.Volumes
+		boot     string
+		expected []string
+	}{
+		{nil, "", nil},
+		{volumes(), "", nil},
+		{volumes("a"), "", []string{"a"}},
+		{volumes("a", "b", "c"), "", []string{"a", "b", "c"}},
+		{volumes("a", "b", "c"), "b", []string{"b", "a", "c"}},
+		{volumes("a", "b", "c"), "c", []string{"c", "a", "b"}},
+		{volumes("a", "b"), "x", []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		server := profitbricks.Server{Entities: &profitbricks.ServerEntities{Volumes: test.volumes}}
+		if test.boot != "" {
+			server.Properties.BootVolume = &profitbricks.ResourceReference{Id: test.boot}
+		}
+
+		var ids []string
+		for _, volume := range cloneSourceVolumes(server) {
+			ids = append(ids, volume.Id)
+		}
+		if !reflect.DeepEqual(ids, test.expected) {
+			t.Errorf("boot volume %q: expected %v, got %v", test.boot, test.expected, ids)
+		}
+	}
+}
+
+func TestCloneVolume(t *testing.T) {
+	d := &Driver{
+		BaseDriver:             &drivers.BaseDriver{MachineName: "web"},
+		VolumeAvailabilityZone: "ZONE_2",
+	}
+	source := profitbricks.Volume{
+		Id: "src",
+		Properties: profitbricks.VolumeProperties{
+			Name:             "source",
+			Type:             "SSD",
+			Size:             50,
+			Bus:              "IDE",
+			AvailabilityZone: "ZONE_1",
+			LicenceType:      "WINDOWS",
+		},
+	}
+
+	tests := []struct {
+		i    int
+		name string
+	}{
+		{0, "web"},
+		{1, "web-1"},
+		{2, "web-2"},
+	}
+
+	for _, test := range tests {
+		expected := profitbricks.VolumeProperties{
+			Name:             test.name,
+			Type:             "SSD",
+			Size:             50,
+			Image:            "snap",
+			Bus:              "IDE",
+			AvailabilityZone: "ZONE_2",
+		}
+		volume := d.cloneVolume(test.i, source, "snap")
+		if !reflect.DeepEqual(volume.Properties, expected) {
+			t.Errorf("volume %d: expected %+v, got %+v", test.i, expected, volume.Properties)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		s, expected string
+	}{
+		{"", `''`},
+		{"ssh-rsa AAAA user@host", `'ssh-rsa AAAA user@host'`},
+		{"ssh-rsa AAAA it's mine", `'ssh-rsa AAAA it'\''s mine'`},
+		{"'; rm -rf / #", `''\''; rm -rf / #'`},
+	}
+
+	for _, test := range tests {
+		if quoted := shellQuote(test.s); quoted != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.s, test.expected, quoted)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package profitbricks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+const hoursPerMonth = 730
+
+// PriceTable holds the prices of the user's contract per location. The driver ships no
+// prices, the table is maintained by the user.
+type PriceTable struct {
+	Currency  string                    `json:"currency"`
+	Locations map[string]LocationPrices `json:"locations"`
+}
+
+// LocationPrices are the hourly prices of the resources of a location. Cores are priced
+// per CPU family.
+type LocationPrices struct {
+	CoreHour  map[string]float64 `json:"coreHour"`
+	RamGBHour float64            `json:"ramGbHour"`
+	HddGBHour float64            `json:"hddGbHour"`
+	SsdGBHour float64            `json:"ssdGbHour"`
+	IpHour    float64            `json:"ipHour"`
+}
+
+// Estimate is the cost of a machine per hour and month.
+type Estimate struct {
+	Machine  string
+	Location string
+	Cores    int
+	Ram      int
+	HddSize  int
+	SsdSize  int
+	Ips      int
+	Hourly   float64
+	Monthly  float64
+}
+
+// DefaultPriceTablePath returns the price table kept in the docker-machine store.
+func DefaultPriceTablePath(storePath string) string {
+	return filepath.Join(storePath, "profitbricks-prices.json")
+}
+
+// LoadPriceTable reads a price table. A missing table is created as a template with
+// zero prices for the given locations, and reported as an error so it gets filled in.
+func LoadPriceTable(path string, locations []string) (*PriceTable, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := writePriceTemplate(path, locations); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Price table %s did not exist, a template with zero prices was written, fill in the prices of your contract", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var table PriceTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("Error reading price table %s: %s", path, err)
+	}
+	return &table, nil
+}
+
+func writePriceTemplate(path string, locations []string) error {
+	table := PriceTable{Currency: "EUR", Locations: map[string]LocationPrices{}}
+	for _, location := range locations {
+		prices := LocationPrices{CoreHour: map[string]float64{}}
+		for _, family := range cpuFamilies {
+			prices.CoreHour[family] = 0
+		}
+		table.Locations[location] = prices
+	}
+
+	data, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// EstimateCost estimates the cost of the machine from its options. extraVolumes are
+// volumes besides the boot volume as TYPE:GB, extraIps reserved IPs besides the one the
+// driver reserves.
+func (d *Driver) EstimateCost(table *PriceTable, extraVolumes []string, extraIps int) (Estimate, error) {
+	usage := d.newMachineUsage(nil)
+	for _, spec := range extraVolumes {
+		volume, err := parseVolumeSpec(spec)
+		if err != nil {
+			return Estimate{}, err
+		}
+		usage.volumes = append(usage.volumes, volume)
+	}
+	usage.ips += extraIps
+
+	estimate, err := table.estimate(d.Location, d.CpuFamily, usage)
+	estimate.Machine = d.MachineName
+	return estimate, err
+}
+
+func (t *PriceTable) estimate(location, cpuFamily string, usage machineUsage) (Estimate, error) {
+	estimate := Estimate{Location: location, Cores: usage.cores, Ram: usage.ram, Ips: usage.ips}
+	prices, ok := t.Locations[location]
+	if !ok {
+		return estimate, fmt.Errorf("Price table has no prices for location %s", location)
+	}
+	coreHour, ok := prices.CoreHour[cpuFamily]
+	if !ok {
+		return estimate, fmt.Errorf("Price table has no core price for CPU family %s in %s", cpuFamily, location)
+	}
+
+	for _, volume := range usage.volumes {
+		if volume.Type == "SSD" {
+			estimate.SsdSize += volume.Size
+		} else {
+			estimate.HddSize += volume.Size
+		}
+	}
+
+	estimate.Hourly = float64(usage.cores)*coreHour +
+		float64(usage.ram)/1024*prices.RamGBHour +
+		float64(estimate.HddSize)*prices.HddGBHour +
+		float64(estimate.SsdSize)*prices.SsdGBHour +
+		float64(usage.ips)*prices.IpHour
+	estimate.Monthly = estimate.Hourly * hoursPerMonth
+	return estimate, nil
+}
+
+// parseVolumeSpec parses a volume given as TYPE:GB, e.g. SSD:100.
+func parseVolumeSpec(spec string) (profitbricks.VolumeProperties, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return profitbricks.VolumeProperties{}, fmt.Errorf("Volume %s is not valid, use TYPE:GB", spec)
+	}
+	volumeType := strings.ToUpper(parts[0])
+	if !contains(diskTypes, volumeType) {
+		return profitbricks.VolumeProperties{}, fmt.Errorf("Volume type %s is not valid, use one of %s", parts[0], strings.Join(diskTypes, ", "))
+	}
+	size, err := strconv.Atoi(parts[1])
+	if err != nil || size < 1 {
+		return profitbricks.VolumeProperties{}, fmt.Errorf("Volume size %s is not valid", parts[1])
+	}
+	return profitbricks.VolumeProperties{Type: volumeType, Size: size}, nil
+}
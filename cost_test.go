@@ -0,0 +1,68 @@
+package profitbricks
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+func TestEstimateCost(t *testing.T) {
+	table := &PriceTable{
+		Currency: "EUR",
+		Locations: map[string]LocationPrices{
+			"de/fra": {
+				CoreHour:  map[string]float64{"AMD_OPTERON": 0.01, "INTEL_XEON": 0.02},
+				RamGBHour: 0.005,
+				HddGBHour: 0.0001,
+				SsdGBHour: 0.0004,
+				IpHour:    0.003,
+			},
+		},
+	}
+	d := &Driver{BaseDriver: &drivers.BaseDriver{MachineName: "test"}, Location: "de/fra", CpuFamily: "INTEL_XEON", Cores: 2, Ram: 4096, DiskSize: 50, DiskType: "HDD"}
+
+	estimate, err := d.EstimateCost(table, []string{"ssd:100"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hourly := 2*0.02 + 4*0.005 + 50*0.0001 + 100*0.0004 + 2*0.003
+	if math.Abs(estimate.Hourly-hourly) > 1e-9 || math.Abs(estimate.Monthly-hourly*hoursPerMonth) > 1e-9 {
+		t.Errorf("Expected %f per hour, got %+v", hourly, estimate)
+	}
+	if estimate.HddSize != 50 || estimate.SsdSize != 100 || estimate.Ips != 2 {
+		t.Errorf("Unexpected usage %+v", estimate)
+	}
+
+	if _, err := d.EstimateCost(table, []string{"NVME:100"}, 0); err == nil {
+		t.Error("Expected an error for an invalid volume")
+	}
+	d.Location = "us/las"
+	if _, err := d.EstimateCost(table, nil, 0); err == nil {
+		t.Error("Expected an error for a location without prices")
+	}
+}
+
+func TestLoadPriceTableTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prices-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prices.json")
+
+	if _, err := LoadPriceTable(path, []string{"us/las"}); err == nil {
+		t.Fatal("Expected an error for a missing price table")
+	}
+	table, err := LoadPriceTable(path, []string{"us/las"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices, ok := table.Locations["us/las"]
+	if !ok || len(prices.CoreHour) != len(cpuFamilies) || prices.RamGBHour != 0 {
+		t.Errorf("Unexpected template %+v", table)
+	}
+}
@@ -0,0 +1,91 @@
+package profitbricks
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// cpuFamilies are the CPU families known to the driver.
+var cpuFamilies = []string{"AMD_OPTERON", "INTEL_XEON"}
+
+// NewAPIDriver returns a driver holding only API credentials, for commands that query
+// the API without a machine.
+func NewAPIDriver(storePath, endpoint, username, password string) *Driver {
+	d := NewDriver("", storePath).(*Driver)
+	d.URL = endpoint
+	d.Username = username
+	d.Password = password
+	return d
+}
+
+// Locations returns all locations, sorted by ID.
+func (d *Driver) Locations() ([]profitbricks.Location, error) {
+	locations, err := d.listLocations()
+	if err != nil {
+		return nil, err
+	}
+	sorted := append([]profitbricks.Location(nil), locations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+	return sorted, nil
+}
+
+// Images returns the images of a location and type, sorted by location and name. Empty
+// filters match all images.
+func (d *Driver) Images(location, imageType string) ([]profitbricks.Image, error) {
+	images, err := d.listImages()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []profitbricks.Image
+	for _, image := range images {
+		if (location == "" || image.Properties.Location == location) && (imageType == "" || image.Properties.ImageType == imageType) {
+			result = append(result, image)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Properties.Location != result[j].Properties.Location {
+			return result[i].Properties.Location < result[j].Properties.Location
+		}
+		return result[i].Properties.Name < result[j].Properties.Name
+	})
+	return result, nil
+}
+
+// Snapshots returns the snapshots of a location, or of all locations, sorted by
+// location and name.
+func (d *Driver) Snapshots(location string) ([]profitbricks.Snapshot, error) {
+	d.setPB()
+	snapshots := profitbricks.ListSnapshots()
+	if snapshots.StatusCode > 299 {
+		return nil, fmt.Errorf("Error occurred while listing snapshots: %s", snapshots.Response)
+	}
+
+	var result []profitbricks.Snapshot
+	for _, snapshot := range snapshots.Items {
+		if location == "" || snapshot.Properties.Location == location {
+			result = append(result, snapshot)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Properties.Location != result[j].Properties.Location {
+			return result[i].Properties.Location < result[j].Properties.Location
+		}
+		return result[i].Properties.Name < result[j].Properties.Name
+	})
+	return result, nil
+}
+
+// CpuFamilies returns the CPU families of a location from its features, or nil when
+// the API lists none there.
+func CpuFamilies(location profitbricks.Location) []string {
+	var families []string
+	for _, feature := range location.Properties.Features {
+		if contains(cpuFamilies, feature) {
+			families = append(families, feature)
+		}
+	}
+	return families
+}
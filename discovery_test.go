@@ -0,0 +1,20 @@
+package profitbricks
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+func TestCpuFamilies(t *testing.T) {
+	location := profitbricks.Location{Properties: profitbricks.LocationProperties{Features: []string{"SSD", "INTEL_XEON"}}}
+	if families := CpuFamilies(location); !reflect.DeepEqual(families, []string{"INTEL_XEON"}) {
+		t.Errorf("Expected the families from the features, got %v", families)
+	}
+
+	location.Properties.Features = []string{"SSD"}
+	if families := CpuFamilies(location); families != nil {
+		t.Errorf("Expected no families, got %v", families)
+	}
+}
@@ -0,0 +1,118 @@
+package profitbricks
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// growRootFilesystemCommand grows the partition holding / to the end of its disk and
+// resizes the filesystem on it.
+const growRootFilesystemCommand = sudoPreamble + `root=$(findmnt -n -o SOURCE /)
+name=$(basename "$root")
+if [ -e "/sys/class/block/$name/partition" ]; then
+	disk=$(lsblk -n -o PKNAME "$root" | head -n 1)
+	part=$(cat "/sys/class/block/$name/partition")
+	command -v growpart >/dev/null || { echo "growpart is not installed" >&2; exit 1; }
+	$SUDO growpart "/dev/$disk" "$part" || [ $? -eq 1 ]
+fi
+case "$(findmnt -n -o FSTYPE /)" in
+	ext*) $SUDO resize2fs "$root" ;;
+	xfs) $SUDO xfs_growfs / ;;
+	btrfs) $SUDO btrfs filesystem resize max / ;;
+	*) echo "Filesystem $(findmnt -n -o FSTYPE /) cannot be grown" >&2; exit 1 ;;
+esac`
+
+// GrowDisk raises the size of a volume the driver manages to size GB. An empty name
+// selects the boot volume, or the volume of machines booted from a CD-ROM, whose root
+// partition and filesystem are then grown over SSH. Otherwise name selects one of the
+// data volumes created for a clone, whose filesystem has to be grown on the machine.
+// Volumes cannot be shrunk.
+func (d *Driver) GrowDisk(name string, size int) error {
+	server, err := d.getServer()
+	if err != nil {
+		return err
+	}
+
+	volume, err := d.managedVolume(server, name)
+	if err != nil {
+		return err
+	}
+	boot := name == "" || volume.Id == bootVolume(server).Id
+
+	grow, err := checkVolumeSize(volume.Properties.Size, size)
+	if err != nil {
+		return err
+	}
+	if !grow {
+		log.Infof("Volume %s already has %d GB", volume.Properties.Name, size)
+		if boot {
+			d.DiskSize = size
+		}
+		return nil
+	}
+
+	log.Infof("Growing volume %s from %d GB to %d GB", volume.Properties.Name, volume.Properties.Size, size)
+	resp := profitbricks.PatchVolume(d.DatacenterId, volume.Id, profitbricks.VolumeProperties{Size: size})
+	if resp.StatusCode > 299 {
+		return errors.New("Error while growing the volume " + resp.Response)
+	}
+	if err := d.waitTillProvisioned(resp.Headers.Get("Location")); err != nil {
+		return err
+	}
+	if !boot {
+		log.Warnf("Grow the filesystem of volume %s on the machine", volume.Properties.Name)
+		return nil
+	}
+	d.DiskSize = size
+
+	if d.BootCdrom {
+		log.Warnf("Machine boots from a CD-ROM, grow the filesystem of its volume on the machine")
+		return nil
+	}
+
+	if server.Properties.VmState != "RUNNING" {
+		log.Warnf("Server is not running, grow the filesystem once it is started")
+		return nil
+	}
+
+	log.Info("Growing the root filesystem")
+	if _, err := runSSHCommand(d, growRootFilesystemCommand); err != nil {
+		return fmt.Errorf("Boot volume was grown to %d GB but the filesystem was not: %s", size, err)
+	}
+	return nil
+}
+
+// managedVolume returns the boot volume when name is empty, otherwise the volume of
+// that name if the driver created it.
+func (d *Driver) managedVolume(server profitbricks.Server, name string) (*profitbricks.Volume, error) {
+	boot := bootVolume(server)
+	if boot == nil {
+		return nil, errors.New("Server has no boot volume")
+	}
+	if name == "" {
+		return boot, nil
+	}
+
+	for i, volume := range server.Entities.Volumes.Items {
+		if volume.Properties.Name != name {
+			continue
+		}
+		if volume.Id != boot.Id && !d.isCloneVolume(volume) {
+			return nil, fmt.Errorf("Volume %s was not created by docker-machine", name)
+		}
+		return &server.Entities.Volumes.Items[i], nil
+	}
+	return nil, fmt.Errorf("Server has no volume %s", name)
+}
+
+// checkVolumeSize reports whether a volume of current GB has to be grown to the
+// requested size, refusing to shrink it.
+func checkVolumeSize(current, requested int) (bool, error) {
+	if requested < current {
+		return false, fmt.Errorf("Shrinking volumes is not supported, the volume has %d GB and %d GB were requested", current, requested)
+	}
+	return requested > current, nil
+}
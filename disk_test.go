@@ -0,0 +1,82 @@
+package profitbricks
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCheckVolumeSize(t *testing.T) {
+	tests := []struct {
+		current, requested int
+		grow, valid        bool
+	}{
+		{50, 100, true, true},
+		{50, 50, false, true},
+		{50, 20, false, false},
+	}
+
+	for _, test := range tests {
+		grow, err := checkVolumeSize(test.current, test.requested)
+		if grow != test.grow || test.valid != (err == nil) {
+			t.Errorf("%d GB to %d GB: got grow %t, error %v", test.current, test.requested, grow, err)
+		}
+	}
+}
+
+func TestGrowDataVolume(t *testing.T) {
+	var patched []string
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PATCH":
+			body, _ := ioutil.ReadAll(r.Body)
+			patched = append(patched, r.URL.Path+" "+string(body))
+			w.Header().Set("Location", "http://"+r.Host+"/requests/1/status")
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/requests/1/status":
+			w.Write([]byte(`{"metadata":{"status":"DONE"}}`))
+		case r.URL.Path == "/datacenters/dc/servers/srv":
+			w.Write([]byte(`{"id":"srv","properties":{"vmState":"SHUTOFF"},"entities":{"volumes":{"items":[
+				{"id":"boot","properties":{"name":"test","size":10}},
+				{"id":"clone-1","properties":{"name":"test-1","size":20}},
+				{"id":"data","properties":{"name":"data","size":20}}]}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer done()
+	d.DiskSize = 10
+
+	if err := d.GrowDisk("test-1", 30); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`/datacenters/dc/volumes/clone-1 {"size":30}`}
+	if !reflect.DeepEqual(patched, expected) {
+		t.Errorf("Expected %v, got %v", expected, patched)
+	}
+	if d.DiskSize != 10 {
+		t.Errorf("Growing a data volume changed the disk size to %d GB", d.DiskSize)
+	}
+
+	patched = nil
+	for _, name := range []string{"data", "missing"} {
+		if err := d.GrowDisk(name, 30); err == nil {
+			t.Errorf("Expected an error growing volume %s", name)
+		}
+	}
+	if err := d.GrowDisk("test-1", 10); err == nil {
+		t.Error("Expected an error shrinking a data volume")
+	}
+	if len(patched) > 0 {
+		t.Errorf("Expected no volume to be patched, got %v", patched)
+	}
+
+	if err := d.GrowDisk("", 40); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{`/datacenters/dc/volumes/boot {"size":40}`}
+	if !reflect.DeepEqual(patched, expected) || d.DiskSize != 40 {
+		t.Errorf("Expected the boot volume to grow to 40 GB, patched %v, disk size %d GB", patched, d.DiskSize)
+	}
+}
@@ -0,0 +1,93 @@
+package profitbricks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+// cleanTemplateCommand clears the machine specific state of a host before its boot
+// volume is used as a template. SSH host keys are regenerated on the next boot.
+const cleanTemplateCommand = sudoPreamble + `if [ -d /etc/systemd/system ]; then
+	$SUDO tee /etc/systemd/system/regenerate-ssh-host-keys.service >/dev/null <<'UNIT'
+[Unit]
+Description=Regenerate SSH host keys
+Before=ssh.service sshd.service
+ConditionPathExistsGlob=!/etc/ssh/ssh_host_*_key
+
+[Service]
+Type=oneshot
+ExecStart=/usr/bin/ssh-keygen -A
+
+[Install]
+WantedBy=multi-user.target
+UNIT
+	$SUDO systemctl enable regenerate-ssh-host-keys.service
+fi
+$SUDO systemctl stop docker 2>/dev/null || $SUDO service docker stop 2>/dev/null || true
+$SUDO rm -f /etc/docker/key.json /etc/docker/ca.pem /etc/docker/server.pem /etc/docker/server-key.pem
+if command -v cloud-init >/dev/null && cloud-init clean --help >/dev/null 2>&1; then
+	$SUDO cloud-init clean --logs
+else
+	$SUDO rm -rf /var/lib/cloud/instances /var/lib/cloud/instance /var/lib/cloud/data
+fi
+$SUDO rm -f /etc/ssh/ssh_host_*
+[ ! -f /etc/machine-id ] || $SUDO truncate -s 0 /etc/machine-id
+$SUDO rm -f /var/lib/dbus/machine-id
+sync`
+
+// GoldenImage is a snapshot baked from a machine, along with the SSH key authorized on
+// it that machines created from it have to use.
+type GoldenImage struct {
+	SnapshotId   string
+	SnapshotName string
+	KeyPath      string
+}
+
+// Bake clears the machine specific state of the host, stops it and snapshots its boot
+// volume. The machine's SSH key is kept in the store next to the snapshot name, so it
+// outlives the machine.
+func (d *Driver) Bake(name string) (*GoldenImage, error) {
+	if name == "" {
+		name = d.MachineName + "-golden-" + time.Now().UTC().Format(snapshotTimeFormat)
+	}
+
+	keyDir := filepath.Join(d.StorePath, "snapshots", name)
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		return nil, err
+	}
+	keyPath := filepath.Join(keyDir, "id_rsa")
+	if err := mcnutils.CopyFile(d.GetSSHKeyPath(), keyPath); err != nil {
+		return nil, err
+	}
+	if err := mcnutils.CopyFile(d.publicSSHKeyPath(), keyPath+".pub"); err != nil {
+		return nil, err
+	}
+
+	log.Info("Clearing machine specific state")
+	if _, err := runSSHCommand(d, cleanTemplateCommand); err != nil {
+		return nil, fmt.Errorf("Clearing the machine specific state of %s failed, the machine may no longer be usable: %s", d.MachineName, err)
+	}
+
+	log.Info("Stopping the server")
+	if err := d.shutdown(); err != nil {
+		return nil, d.clearedError(err)
+	}
+
+	snapshot, err := d.snapshotBootVolume(name, fmt.Sprintf("docker-machine golden image baked from %s", d.MachineName))
+	if err != nil {
+		return nil, d.clearedError(err)
+	}
+	return &GoldenImage{SnapshotId: snapshot.Id, SnapshotName: name, KeyPath: keyPath}, nil
+}
+
+// clearedError explains that baking failed after the machine specific state of the
+// host was cleared, which leaves the machine without its SSH host keys and Docker
+// identity.
+func (d *Driver) clearedError(err error) error {
+	return fmt.Errorf("Baking failed after the machine specific state of %s was cleared, the machine is no longer usable, remove it or bake it again: %s", d.MachineName, err)
+}
@@ -0,0 +1,85 @@
+package profitbricks
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+func TestBake(t *testing.T) {
+	defer func(run func(drivers.Driver, string) (string, error)) { runSSHCommand = run }(runSSHCommand)
+	var commands []string
+	runSSHCommand = func(_ drivers.Driver, command string) (string, error) {
+		commands = append(commands, command)
+		return "", nil
+	}
+
+	snapshotStatus := http.StatusAccepted
+	var snapshots []string
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/datacenters/dc/volumes/boot/create-snapshot":
+			r.ParseForm()
+			snapshots = append(snapshots, r.PostForm.Get("name"))
+			w.Header().Set("Location", "http://"+r.Host+"/requests/1/status")
+			w.WriteHeader(snapshotStatus)
+			w.Write([]byte(`{"id":"snap","properties":{"name":"golden"}}`))
+		case r.Method == "POST":
+			w.Header().Set("Location", "http://"+r.Host+"/requests/1/status")
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/requests/1/status":
+			w.Write([]byte(`{"metadata":{"status":"DONE"}}`))
+		case r.URL.Path == "/datacenters/dc/servers/srv":
+			w.Write([]byte(`{"id":"srv","properties":{"vmState":"SHUTOFF"},"entities":{"volumes":{"items":[
+				{"id":"boot","properties":{"name":"test","size":10}}]}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer done()
+
+	storePath, err := ioutil.TempDir("", "machine-store-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+	d.StorePath = storePath
+	d.StopGracePeriod = 5
+	d.SSHKeyPath = filepath.Join(storePath, "id_rsa")
+	ioutil.WriteFile(d.SSHKeyPath, []byte("private"), 0600)
+	ioutil.WriteFile(d.SSHKeyPath+".pub", []byte("public"), 0600)
+
+	golden, err := d.Bake("golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 2 || commands[0] != cleanTemplateCommand {
+		t.Errorf("Expected the machine to be cleared and shut down, ran %d commands", len(commands))
+	}
+	if len(snapshots) != 1 || snapshots[0] != "golden" {
+		t.Errorf("Expected a snapshot named golden, got %v", snapshots)
+	}
+	if golden.SnapshotId != "snap" || golden.SnapshotName != "golden" {
+		t.Errorf("Unexpected golden image %+v", golden)
+	}
+
+	keyPath := filepath.Join(storePath, "snapshots", "golden", "id_rsa")
+	if golden.KeyPath != keyPath {
+		t.Errorf("Expected the key at %s, got %s", keyPath, golden.KeyPath)
+	}
+	for path, expected := range map[string]string{keyPath: "private", keyPath + ".pub": "public"} {
+		if content, err := ioutil.ReadFile(path); err != nil || string(content) != expected {
+			t.Errorf("Expected %s to hold the machine's key, got %q, %v", path, content, err)
+		}
+	}
+
+	snapshotStatus = http.StatusInternalServerError
+	if _, err := d.Bake("golden-2"); err == nil || !strings.Contains(err.Error(), "no longer usable") {
+		t.Errorf("Expected an error saying the machine is no longer usable, got %v", err)
+	}
+}
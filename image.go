@@ -0,0 +1,342 @@
+package profitbricks
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+var (
+	imageMatchModes = []string{"substring", "exact", "glob", "regex"}
+	imageTypes      = []string{"HDD", "CDROM"}
+
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	imageDatePattern    = regexp.MustCompile(`(?:19|20)\d{2}-?(?:0[1-9]|1[0-2])-?(?:0[1-9]|[12]\d|3[01])`)
+	imageVersionPattern = regexp.MustCompile(`\d+(?:\.\d+)*`)
+)
+
+// findImage resolves imageName to an image alias, a snapshot or an image ID, and pins
+// the result in the driver. UUIDs are looked up directly, aliases and snapshots are
+// matched exactly, images by the configured match mode with private images preferred.
+// Several matching images are an error unless the newest one was asked for.
+func (d *Driver) findImage(imageName string) (string, error) {
+	d.setPB()
+	d.UseAlias = false
+	d.UseSnapshot = false
+	d.image = nil
+
+	if uuidPattern.MatchString(imageName) {
+		image, err := d.findImageById(imageName)
+		if err != nil {
+			return "", err
+		}
+		d.UseSnapshot = image.Type == "snapshot"
+		d.image = image
+		d.ImageId, d.ImageName = image.Id, image.Properties.Name
+		return image.Id, nil
+	}
+
+	//aliases and snapshots are disk images, CD-ROMs are only found by the extended search
+	var aliases []string
+	if !d.BootCdrom {
+		location, err := d.findLocation(d.Location)
+		if err != nil {
+			log.Debugf("Skipping image aliases: %s", err)
+		} else {
+			aliases = location.Properties.ImageAliases
+			for _, alias := range aliases {
+				if alias == imageName {
+					d.UseAlias = true
+					d.ImageId, d.ImageName = imageName, imageName
+					return imageName, nil
+				}
+			}
+		}
+
+		if snapshot := d.findSnapshot(imageName); snapshot != nil {
+			d.UseSnapshot = true
+			d.image = snapshot
+			d.ImageId, d.ImageName = snapshot.Id, snapshot.Properties.Name
+			return snapshot.Id, nil
+		}
+	}
+
+	match, err := imageMatcher(d.ImageMatch, imageName)
+	if err != nil {
+		return "", err
+	}
+
+	images, err := d.listImages()
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []profitbricks.Image
+	var rejected []string
+	usable := append([]string(nil), aliases...)
+	for _, image := range images {
+		reasons := d.imageUnusable(image)
+		if len(reasons) == 0 {
+			usable = append(usable, image.Properties.Name)
+		}
+		if image.Properties.Name == "" || !match(image.Properties.Name) {
+			continue
+		}
+		if len(reasons) > 0 {
+			rejected = append(rejected, fmt.Sprintf("  %s (%s): %s", image.Properties.Name, image.Id, strings.Join(reasons, ", ")))
+			continue
+		}
+		candidates = append(candidates, image)
+	}
+
+	if len(candidates) == 0 {
+		if len(rejected) > 0 {
+			return "", fmt.Errorf("No image matching %s can be used in %s:\n%s", imageName, d.Location, strings.Join(rejected, "\n"))
+		}
+		return "", fmt.Errorf("The image/alias %s %s does not exist%s", imageName, d.Location, didYouMean(imageName, usable))
+	}
+	if private := privateImages(candidates); len(private) > 0 && len(private) < len(candidates) {
+		log.Infof("Image %s matches private and public images, using the private ones", imageName)
+		candidates = private
+	}
+	if len(candidates) > 1 {
+		if !d.ImageNewest {
+			return "", ambiguousImageError(imageName, candidates)
+		}
+		sortImagesNewestFirst(candidates)
+		log.Infof("Image %s matches %d images, using the newest %s", imageName, len(candidates), candidates[0].Properties.Name)
+	}
+
+	d.image = &candidates[0]
+	d.ImageId, d.ImageName = d.image.Id, d.image.Properties.Name
+	return d.ImageId, nil
+}
+
+// findImageById looks an image or snapshot up by ID, and checks it can boot the
+// machine.
+func (d *Driver) findImageById(id string) (*profitbricks.Image, error) {
+	image := profitbricks.GetImage(id)
+	if image.StatusCode == 404 {
+		snapshot := profitbricks.GetSnapshot(id)
+		if snapshot.StatusCode == 404 {
+			return nil, fmt.Errorf("No image or snapshot with ID %s exists", id)
+		}
+		if snapshot.StatusCode > 299 {
+			return nil, fmt.Errorf("Error occurred while fetching snapshot %s: %s", id, snapshot.Response)
+		}
+		image = snapshotImage(snapshot)
+	} else if image.StatusCode > 299 {
+		return nil, fmt.Errorf("Error occurred while fetching image %s: %s", id, image.Response)
+	}
+
+	if reasons := d.imageUnusable(image); len(reasons) > 0 {
+		return nil, fmt.Errorf("Image %s (%s) cannot be used: %s", image.Properties.Name, id, strings.Join(reasons, ", "))
+	}
+	if image.Properties.Public {
+		log.Debugf("Using public image %s", image.Properties.Name)
+	} else {
+		log.Debugf("Using private %s %s", image.Type, image.Properties.Name)
+	}
+	return &image, nil
+}
+
+// requiredImageType returns the image type the boot volume is created from. It is
+// independent of the volume's storage type (HDD, SSD).
+func (d *Driver) requiredImageType() string {
+	if d.BootCdrom {
+		return "CDROM"
+	}
+	if d.ImageType == "" {
+		return "HDD"
+	}
+	return d.ImageType
+}
+
+// imageUnusable explains which filters rule the image out for booting the machine.
+func (d *Driver) imageUnusable(image profitbricks.Image) []string {
+	var reasons []string
+	if image.Properties.Location != d.Location {
+		reasons = append(reasons, fmt.Sprintf("location is %s, not %s", image.Properties.Location, d.Location))
+	}
+	if imageType := d.requiredImageType(); image.Properties.ImageType != imageType {
+		if d.BootCdrom {
+			reasons = append(reasons, fmt.Sprintf("image type is %s, booting from a CD-ROM needs CDROM", image.Properties.ImageType))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("image type is %s, not %s", image.Properties.ImageType, imageType))
+		}
+	}
+	return reasons
+}
+
+func privateImages(images []profitbricks.Image) []profitbricks.Image {
+	var private []profitbricks.Image
+	for _, image := range images {
+		if !image.Properties.Public {
+			private = append(private, image)
+		}
+	}
+	return private
+}
+
+// imageMatcher returns a function matching image names against pattern.
+func imageMatcher(mode, pattern string) (func(name string) bool, error) {
+	switch mode {
+	case "", "substring":
+		pattern = strings.ToLower(pattern)
+		return func(name string) bool { return strings.Contains(strings.ToLower(name), pattern) }, nil
+	case "exact":
+		return func(name string) bool { return name == pattern }, nil
+	case "glob":
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Image glob %s is not valid: %s", pattern, err)
+		}
+		return func(name string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		}, nil
+	case "regex":
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Image regex %s is not valid: %s", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+	return nil, fmt.Errorf("Image match mode %s is not valid, use one of %s", mode, strings.Join(imageMatchModes, ", "))
+}
+
+func ambiguousImageError(imageName string, candidates []profitbricks.Image) error {
+	sortImagesNewestFirst(candidates)
+	lines := make([]string, len(candidates))
+	for i, image := range candidates {
+		lines[i] = fmt.Sprintf("  %s (%s)", image.Properties.Name, image.Id)
+	}
+	return fmt.Errorf("Image %s matches %d images, use a more specific name or --profitbricks-image-newest:\n%s",
+		imageName, len(candidates), strings.Join(lines, "\n"))
+}
+
+// sortImagesNewestFirst orders images by the version embedded in their name, then by
+// the embedded date, then by creation date.
+func sortImagesNewestFirst(images []profitbricks.Image) {
+	sort.SliceStable(images, func(i, j int) bool {
+		return compareImages(images[i], images[j]) > 0
+	})
+}
+
+func compareImages(a, b profitbricks.Image) int {
+	dateA, versionA := imageNameVersion(a.Properties.Name)
+	dateB, versionB := imageNameVersion(b.Properties.Name)
+	if c := compareVersions(versionA, versionB); c != 0 {
+		return c
+	}
+	if c := strings.Compare(dateA, dateB); c != 0 {
+		return c
+	}
+
+	var createdA, createdB int64
+	if a.Metadata != nil {
+		createdA = a.Metadata.CreatedDate.Unix()
+	}
+	if b.Metadata != nil {
+		createdB = b.Metadata.CreatedDate.Unix()
+	}
+	switch {
+	case createdA > createdB:
+		return 1
+	case createdA < createdB:
+		return -1
+	}
+	return 0
+}
+
+// imageNameVersion extracts the date (as YYYYMMDD) and the first version number
+// embedded in an image name, e.g. "Ubuntu-16.04-LTS-server-2017-05-01".
+func imageNameVersion(name string) (string, []int) {
+	date := imageDatePattern.FindString(name)
+	if date != "" {
+		name = strings.Replace(name, date, "", 1)
+		date = strings.Replace(date, "-", "", -1)
+	}
+
+	var version []int
+	for _, part := range strings.Split(imageVersionPattern.FindString(name), ".") {
+		if n, err := strconv.Atoi(part); err == nil {
+			version = append(version, n)
+		}
+	}
+	return date, version
+}
+
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x > y:
+			return 1
+		case x < y:
+			return -1
+		}
+	}
+	return 0
+}
+
+// findSnapshot looks a snapshot in the driver's location up by name or ID, and returns
+// it as an image so it can be checked like one.
+func (d *Driver) findSnapshot(nameOrId string) *profitbricks.Image {
+	snapshots := profitbricks.ListSnapshots()
+	if snapshots.StatusCode > 299 {
+		log.Debugf("Error occurred while listing snapshots: %s", snapshots.Response)
+		return nil
+	}
+
+	for _, snapshot := range snapshots.Items {
+		if snapshot.Properties.Location != d.Location {
+			continue
+		}
+		if snapshot.Id == nameOrId || snapshot.Properties.Name == nameOrId {
+			image := snapshotImage(snapshot)
+			return &image
+		}
+	}
+	return nil
+}
+
+// snapshotImage converts a snapshot to the image it can be used as.
+func snapshotImage(snapshot profitbricks.Snapshot) profitbricks.Image {
+	p := snapshot.Properties
+	return profitbricks.Image{
+		Id:       snapshot.Id,
+		Type:     "snapshot",
+		Metadata: &snapshot.Metadata,
+		Properties: profitbricks.ImageProperties{
+			Name:                p.Name,
+			Description:         p.Description,
+			Location:            p.Location,
+			Size:                p.Size,
+			CpuHotPlug:          p.CpuHotPlug,
+			CpuHotUnplug:        p.CpuHotUnplug,
+			RamHotPlug:          p.RamHotPlug,
+			RamHotUnplug:        p.RamHotUnplug,
+			NicHotPlug:          p.NicHotPlug,
+			NicHotUnplug:        p.NicHotUnplug,
+			DiscVirtioHotPlug:   p.DiscVirtioHotPlug,
+			DiscVirtioHotUnplug: p.DiscVirtioHotUnplug,
+			DiscScsiHotPlug:     p.DiscScsiHotPlug,
+			DiscScsiHotUnplug:   p.DiscScsiHotUnplug,
+			LicenceType:         p.LicenceType,
+			ImageType:           "HDD",
+		},
+	}
+}
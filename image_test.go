@@ -0,0 +1,115 @@
+package profitbricks
+
+import (
+	"testing"
+
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+func TestImageMatcher(t *testing.T) {
+	tests := []struct {
+		mode    string
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"", "ubuntu-16", "Ubuntu-16.04-LTS-server-2017-05-01", true},
+		{"substring", "UBUNTU", "Ubuntu-16.04", true},
+		{"exact", "Ubuntu-16.04", "Ubuntu-16.04", true},
+		{"exact", "Ubuntu-16", "Ubuntu-16.04", false},
+		{"glob", "Ubuntu-16.04-*", "Ubuntu-16.04-LTS-server-2017-05-01", true},
+		{"glob", "Ubuntu-14.*", "Ubuntu-16.04-LTS", false},
+		{"regex", `^Ubuntu-1[46]\.04`, "Ubuntu-14.04-LTS", true},
+		{"regex", `^Debian`, "Ubuntu-14.04-LTS", false},
+	}
+
+	for _, test := range tests {
+		match, err := imageMatcher(test.mode, test.pattern)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error %s", test.mode, test.pattern, err)
+			continue
+		}
+		if match(test.name) != test.match {
+			t.Errorf("%s %s on %s: expected %t", test.mode, test.pattern, test.name, test.match)
+		}
+	}
+
+	if _, err := imageMatcher("fuzzy", "Ubuntu"); err == nil {
+		t.Error("Expected an error for an unknown match mode")
+	}
+	if _, err := imageMatcher("regex", "Ubuntu("); err == nil {
+		t.Error("Expected an error for an invalid regex")
+	}
+}
+
+func TestSortImagesNewestFirst(t *testing.T) {
+	image := func(name string) profitbricks.Image {
+		return profitbricks.Image{Id: name, Properties: profitbricks.ImageProperties{Name: name}}
+	}
+	images := []profitbricks.Image{
+		image("Ubuntu-16.04-LTS-server-2017-05-01"),
+		image("Ubuntu-16.10-server-2017-01-01"),
+		image("Ubuntu-16.04-LTS-server-2017-07-01"),
+		image("Ubuntu-9.10-server-2018-01-01"),
+	}
+
+	sortImagesNewestFirst(images)
+
+	expected := []string{
+		"Ubuntu-16.10-server-2017-01-01",
+		"Ubuntu-16.04-LTS-server-2017-07-01",
+		"Ubuntu-16.04-LTS-server-2017-05-01",
+		"Ubuntu-9.10-server-2018-01-01",
+	}
+	for i, name := range expected {
+		if images[i].Properties.Name != name {
+			t.Errorf("Position %d: expected %s, got %s", i, name, images[i].Properties.Name)
+		}
+	}
+}
+
+func TestImageUnusable(t *testing.T) {
+	image := func(location, imageType string) profitbricks.Image {
+		return profitbricks.Image{Properties: profitbricks.ImageProperties{Location: location, ImageType: imageType}}
+	}
+
+	tests := []struct {
+		driver *Driver
+		image  profitbricks.Image
+		usable bool
+	}{
+		{&Driver{Location: "us/las", DiskType: "HDD"}, image("us/las", "HDD"), true},
+		{&Driver{Location: "us/las", DiskType: "SSD"}, image("us/las", "HDD"), true},
+		{&Driver{Location: "us/las", DiskType: "SSD", ImageType: "HDD"}, image("us/las", "HDD"), true},
+		{&Driver{Location: "us/las", DiskType: "HDD"}, image("de/fra", "HDD"), false},
+		{&Driver{Location: "us/las", DiskType: "HDD"}, image("us/las", "CDROM"), false},
+		{&Driver{Location: "us/las", BootCdrom: true}, image("us/las", "CDROM"), true},
+		{&Driver{Location: "us/las", BootCdrom: true}, image("us/las", "HDD"), false},
+	}
+
+	for _, test := range tests {
+		reasons := test.driver.imageUnusable(test.image)
+		if test.usable && len(reasons) > 0 {
+			t.Errorf("%+v: unexpected reasons %q", test.image.Properties, reasons)
+		}
+		if !test.usable && len(reasons) == 0 {
+			t.Errorf("%+v: expected a reason", test.image.Properties)
+		}
+	}
+
+	d := &Driver{Location: "us/las", ImageType: "HDD"}
+	if reasons := d.imageUnusable(image("de/fra", "CDROM")); len(reasons) != 2 {
+		t.Errorf("Expected the location and image type filters, got %q", reasons)
+	}
+}
+
+func TestPrivateImages(t *testing.T) {
+	images := []profitbricks.Image{
+		{Id: "public", Properties: profitbricks.ImageProperties{Public: true}},
+		{Id: "private"},
+	}
+	private := privateImages(images)
+	if len(private) != 1 || private[0].Id != "private" {
+		t.Errorf("Expected only the private image, got %+v", private)
+	}
+}
@@ -0,0 +1,67 @@
+package profitbricks
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+)
+
+const (
+	passwordChars          = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	generatedPasswordSize  = 20
+	consolePasswordFile    = "console-password"
+	minImagePasswordLength = 8
+	maxImagePasswordLength = 50
+)
+
+// validateImagePassword checks a password against the rules of the API: 8 to 50
+// characters, letters and digits only.
+func validateImagePassword(password string) error {
+	if len(password) < minImagePasswordLength || len(password) > maxImagePasswordLength {
+		return fmt.Errorf("Image password must be %d to %d characters long", minImagePasswordLength, maxImagePasswordLength)
+	}
+	for _, c := range password {
+		if !strings.ContainsRune(passwordChars, c) {
+			return errors.New("Image password may only contain the characters a-z, A-Z and 0-9")
+		}
+	}
+	return nil
+}
+
+// generateImagePassword returns a random password with lower case letters, upper case
+// letters and digits.
+func generateImagePassword() (string, error) {
+	max := big.NewInt(int64(len(passwordChars)))
+	for {
+		password := make([]byte, generatedPasswordSize)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			password[i] = passwordChars[n.Int64()]
+		}
+		p := string(password)
+		if strings.ContainsAny(p, passwordChars[:26]) && strings.ContainsAny(p, passwordChars[26:52]) && strings.ContainsAny(p, passwordChars[52:]) {
+			return p, nil
+		}
+	}
+}
+
+// ConsolePassword returns the generated console password of the machine, which is kept
+// in the machine directory rather than in config.json.
+func (d *Driver) ConsolePassword() (string, error) {
+	password, err := ioutil.ReadFile(d.ResolveStorePath(consolePasswordFile))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("Machine %s has no generated console password", d.MachineName)
+	}
+	return string(password), err
+}
+
+func (d *Driver) saveConsolePassword() error {
+	return ioutil.WriteFile(d.ResolveStorePath(consolePasswordFile), []byte(d.ImagePassword), 0600)
+}
@@ -0,0 +1,41 @@
+package profitbricks
+
+import "testing"
+
+func TestValidateImagePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{"Secret123", true},
+		{"short1A", false},
+		{"with space 123", false},
+		{"symbols!123A", false},
+		{"a123456789b123456789c123456789d123456789e123456789", true},
+		{"a123456789b123456789c123456789d123456789e123456789f", false},
+	}
+
+	for _, test := range tests {
+		if err := validateImagePassword(test.password); test.valid != (err == nil) {
+			t.Errorf("%q: expected valid %t, got %v", test.password, test.valid, err)
+		}
+	}
+}
+
+func TestGenerateImagePassword(t *testing.T) {
+	first, err := generateImagePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateImagePassword(first); err != nil {
+		t.Errorf("Generated password %q is not valid: %s", first, err)
+	}
+
+	second, err := generateImagePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("Generated passwords are not random")
+	}
+}
@@ -0,0 +1,114 @@
+package profitbricks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// PlanStep is an API request Create would submit.
+type PlanStep struct {
+	Operation string      `json:"operation"`
+	Spec      interface{} `json:"spec,omitempty"`
+}
+
+// Plan returns the API requests Create would submit for the checked options, without
+// creating anything. Values that are only known while creating, like IDs of new
+// resources, reserved IPs and generated keys, are shown as placeholders.
+func (d *Driver) Plan() ([]PlanStep, error) {
+	var steps []PlanStep
+	var volumes []profitbricks.Volume
+	var snapshots []string
+
+	if d.CloneFrom != "" {
+		source, server, err := d.prepareClone()
+		if err != nil {
+			return nil, err
+		}
+		for i, volume := range cloneSourceVolumes(server) {
+			snapshot := fmt.Sprintf("{snapshot-%d}", i)
+			steps = append(steps, PlanStep{
+				Operation: fmt.Sprintf("POST /datacenters/%s/volumes/%s/create-snapshot", source.DatacenterId, volume.Id),
+				Spec:      map[string]string{"name": fmt.Sprintf("%s-clone-%d-<timestamp>", d.MachineName, i), "description": "docker-machine clone of " + source.MachineName},
+			})
+			volumes = append(volumes, d.cloneVolume(i, volume, snapshot))
+			snapshots = append(snapshots, snapshot)
+		}
+	} else {
+		if d.ImageId == "" {
+			if _, err := d.findImage(d.Image); err != nil {
+				return nil, err
+			}
+		}
+		if d.BootCdrom {
+			volumes = d.cdromVolumes()
+		} else {
+			volumes = []profitbricks.Volume{d.imageVolume(d.ImageId, d.planSSHKey(), d.planImagePassword())}
+		}
+	}
+
+	steps = append(steps, PlanStep{Operation: "POST /ipblocks", Spec: d.ipBlockSpec()})
+
+	datacenterId := d.DatacenterId
+	if datacenterId == "" {
+		datacenterId = "{datacenter}"
+		steps = append(steps, PlanStep{Operation: "POST /datacenters", Spec: d.datacenterSpec()})
+	}
+	steps = append(steps, PlanStep{Operation: fmt.Sprintf("POST /datacenters/%s/lans", datacenterId), Spec: d.lanSpec()})
+
+	server := d.serverSpec(volumes, 0, []string{"<reserved IP>"})
+	steps = append(steps, PlanStep{Operation: fmt.Sprintf("POST /datacenters/%s/servers (NIC in the new LAN, no firewall rules)", datacenterId), Spec: server})
+
+	if d.BootCdrom {
+		steps = append(steps,
+			PlanStep{
+				Operation: fmt.Sprintf("POST /datacenters/%s/servers/{server}/cdroms (when the server was not created with it)", datacenterId),
+				Spec:      map[string]string{"id": d.ImageId},
+			},
+			PlanStep{Operation: fmt.Sprintf("POST /datacenters/%s/servers/{server}/reboot (when the CD-ROM was attached)", datacenterId)},
+		)
+	}
+	for _, snapshot := range snapshots {
+		steps = append(steps, PlanStep{Operation: fmt.Sprintf("DELETE /snapshots/%s", snapshot)})
+	}
+	return steps, nil
+}
+
+func (d *Driver) planSSHKey() string {
+	if d.PrivateKeyPath != "" {
+		if key, err := ioutil.ReadFile(d.PrivateKeyPath + ".pub"); err == nil {
+			return strings.TrimSpace(string(key))
+		}
+	}
+	return "<generated SSH public key>"
+}
+
+func (d *Driver) planImagePassword() string {
+	if d.ImagePassword != "" {
+		return "<image password>"
+	}
+	if d.GenerateImagePassword {
+		return "<generated password>"
+	}
+	return ""
+}
+
+// printPlan logs the plan of a dry run, and returns an error so the machine is not
+// created.
+func (d *Driver) printPlan() error {
+	steps, err := d.Plan()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(steps, "", "  ")
+	if err != nil {
+		return err
+	}
+	log.Info(string(data))
+	return errors.New("Dry run, nothing was created")
+}
@@ -0,0 +1,58 @@
+package profitbricks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+func TestPlan(t *testing.T) {
+	d := &Driver{
+		BaseDriver:    &drivers.BaseDriver{MachineName: "test"},
+		Location:      "us/las",
+		Cores:         2,
+		Ram:           2048,
+		DiskSize:      50,
+		DiskType:      "HDD",
+		ImageId:       "ubuntu:latest",
+		UseAlias:      true,
+		ImagePassword: "secret123",
+	}
+
+	steps, err := d.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var operations []string
+	for _, step := range steps {
+		operations = append(operations, strings.Fields(step.Operation)[0]+" "+strings.Fields(step.Operation)[1])
+	}
+	expected := []string{
+		"POST /ipblocks",
+		"POST /datacenters",
+		"POST /datacenters/{datacenter}/lans",
+		"POST /datacenters/{datacenter}/servers",
+	}
+	if strings.Join(operations, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected operations %v", operations)
+	}
+
+	data, err := json.Marshal(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret123") {
+		t.Error("The plan contains the image password")
+	}
+	if !strings.Contains(string(data), `"imageAlias":"ubuntu:latest"`) {
+		t.Errorf("The plan does not use the image alias: %s", data)
+	}
+
+	d.DatacenterId = "dc"
+	if steps, _ := d.Plan(); len(steps) != 3 {
+		t.Errorf("Expected no datacenter to be created, got %d steps", len(steps))
+	}
+}
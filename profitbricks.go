@@ -11,6 +11,7 @@ import (
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/profitbricks/profitbricks-sdk-go"
@@ -36,13 +37,40 @@ type Driver struct {
 	CpuFamily              string
 	DCExists               bool
 	UseAlias               bool
+	UseSnapshot            bool
+	ImageType              string
+	ImageMatch             string
+	ImageNewest            bool
+	ImageId                string
+	ImageName              string
+	PrivateKeyPath         string
+	CloneFrom              string
+	ImagePassword          string `json:"-"`
+	GenerateImagePassword  bool
+	BootCdrom              bool
 	LanId                  string
+	NicId                  string
+	VolumeBus              string
+	LicenceType            string
+	HotPlug                []string
+	CacheTTL               int
+	StopGracePeriod        int
+	DryRun                 bool `json:"-"`
+	RefreshCache           bool `json:"-"`
+	image                  *profitbricks.Image
+	locations              []profitbricks.Location
+	images                 []profitbricks.Image
 }
 
 const (
-	defaultRegion = "us/las"
-	defaultSize   = 10
-	waitCount     = 1000
+	defaultRegion     = "us/las"
+	defaultSize       = 10
+	waitCount         = 1000
+	stateWaitCount    = 120
+	stateWaitInterval = 5 * time.Second
+	// stoppingWaitCount bounds the wait of Stop on a server reported as stopping, which
+	// may be busy with another change
+	stoppingWaitCount = 12
 )
 
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
@@ -85,7 +113,70 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			EnvVar: "PROFITBRICKS_IMAGE",
 			Name:   "profitbricks-image",
 			Value:  "Ubuntu-16.04",
-			Usage:  "ProfitBricks image",
+			Usage:  "ProfitBricks image, image alias, or snapshot name or ID",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "PROFITBRICKS_IMAGE_PASSWORD",
+			Name:   "profitbricks-image-password",
+			Usage:  "ProfitBricks console password of the boot volume's root user, 8 to 50 letters and digits",
+		},
+		mcnflag.BoolFlag{
+			EnvVar: "PROFITBRICKS_GENERATE_IMAGE_PASSWORD",
+			Name:   "profitbricks-generate-image-password",
+			Usage:  "Generate a console password, stored in the machine directory",
+		},
+		mcnflag.StringFlag{
+			Name:  "profitbricks-clone-from",
+			Usage: "Name of a machine in the same store to create this machine as a copy of",
+		},
+		mcnflag.BoolFlag{
+			EnvVar: "PROFITBRICKS_BOOT_CDROM",
+			Name:   "profitbricks-boot-cdrom",
+			Usage:  "Boot from the CD-ROM image named by --profitbricks-image, with an empty volume attached",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "PROFITBRICKS_SSH_USER",
+			Name:   "profitbricks-ssh-user",
+			Value:  drivers.DefaultSSHUser,
+			Usage:  "SSH user of the image",
+		},
+		mcnflag.IntFlag{
+			EnvVar: "PROFITBRICKS_CACHE_TTL",
+			Name:   "profitbricks-cache-ttl",
+			Value:  defaultCacheTTL,
+			Usage:  "Seconds to cache locations and images in the machine store, 0 disables the cache",
+		},
+		mcnflag.BoolFlag{
+			EnvVar: "PROFITBRICKS_REFRESH_CACHE",
+			Name:   "profitbricks-refresh-cache",
+			Usage:  "Fetch locations and images from the API and refresh the cache",
+		},
+		mcnflag.BoolFlag{
+			EnvVar: "PROFITBRICKS_DRY_RUN",
+			Name:   "profitbricks-dry-run",
+			Usage:  "Check the options and print the API requests creating the machine would submit, without creating it",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "PROFITBRICKS_IMAGE_MATCH",
+			Name:   "profitbricks-image-match",
+			Value:  "substring",
+			Usage:  "How --profitbricks-image is matched against image names (substring, exact, glob, regex)",
+		},
+		mcnflag.BoolFlag{
+			EnvVar: "PROFITBRICKS_IMAGE_NEWEST",
+			Name:   "profitbricks-image-newest",
+			Usage:  "Pick the image with the newest version or date when several images match",
+		},
+		mcnflag.IntFlag{
+			EnvVar: "PROFITBRICKS_STOP_GRACE_PERIOD",
+			Name:   "profitbricks-stop-grace-period",
+			Value:  defaultStopGracePeriod,
+			Usage:  "Seconds to wait for a clean shutdown of the guest OS before stopping the server through the API, 0 always stops it through the API",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "PROFITBRICKS_SSH_KEY_PATH",
+			Name:   "profitbricks-ssh-key-path",
+			Usage:  "Path to an existing private SSH key to use instead of generating one, required for snapshots",
 		},
 		mcnflag.StringFlag{
 			EnvVar: "PROFITBRICKS_LOCATION",
@@ -99,6 +190,12 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  "HDD",
 			Usage:  "ProfitBricks disk type (HDD, SSD)",
 		},
+		mcnflag.StringFlag{
+			EnvVar: "PROFITBRICKS_IMAGE_TYPE",
+			Name:   "profitbricks-image-type",
+			Value:  "HDD",
+			Usage:  "ProfitBricks image type (HDD, CDROM), CDROM requires --profitbricks-boot-cdrom",
+		},
 		mcnflag.StringFlag{
 			EnvVar: "PROFITBRICKS_CPU_FAMILY",
 			Name:   "profitbricks-cpu-family",
@@ -117,15 +214,33 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		mcnflag.StringFlag{
 			Name:  "profitbricks-server-availability-zone",
 			Value: "AUTO",
-			Usage: "ProfitBricks Server Availability Zone (AUTO, ZONE_1, ZONE_2, ZONE_3)",
+			Usage: "ProfitBricks Server Availability Zone (AUTO, ZONE_1, ZONE_2)",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "PROFITBRICKS_VOLUME_BUS",
+			Name:   "profitbricks-volume-bus",
+			Value:  "VIRTIO",
+			Usage:  "ProfitBricks boot volume bus (VIRTIO, IDE)",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "PROFITBRICKS_LICENCE_TYPE",
+			Name:   "profitbricks-licence-type",
+			Usage:  "ProfitBricks boot volume licence type (LINUX, WINDOWS, WINDOWS2016, UNKNOWN, OTHER), defaults to the image's",
+		},
+		mcnflag.StringSliceFlag{
+			EnvVar: "PROFITBRICKS_HOT_PLUG",
+			Name:   "profitbricks-hot-plug",
+			Usage:  "ProfitBricks boot volume hot-plug capabilities (cpuHotPlug, cpuHotUnplug, ramHotPlug, ramHotUnplug, nicHotPlug, nicHotUnplug, discVirtioHotPlug, discVirtioHotUnplug, discScsiHotPlug, discScsiHotUnplug)",
 		},
 	}
 }
 
 func NewDriver(hostName, storePath string) drivers.Driver {
 	return &Driver{
-		Size:     defaultSize,
-		Location: defaultRegion,
+		Size:            defaultSize,
+		Location:        defaultRegion,
+		CacheTTL:        defaultCacheTTL,
+		StopGracePeriod: defaultStopGracePeriod,
 		BaseDriver: &drivers.BaseDriver{
 			MachineName: hostName,
 			StorePath:   storePath,
@@ -152,6 +267,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.Ram = flags.Int("profitbricks-ram")
 	d.Location = flags.String("profitbricks-location")
 	d.DiskType = flags.String("profitbricks-disk-type")
+	d.ImageType = strings.ToUpper(flags.String("profitbricks-image-type"))
 	d.SwarmMaster = flags.Bool("swarm-master")
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
@@ -159,6 +275,21 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.DatacenterId = flags.String("profitbricks-datacenter-id")
 	d.VolumeAvailabilityZone = flags.String("profitbricks-volume-availability-zone")
 	d.ServerAvailabilityZone = flags.String("profitbricks-server-availability-zone")
+	d.VolumeBus = strings.ToUpper(flags.String("profitbricks-volume-bus"))
+	d.LicenceType = strings.ToUpper(flags.String("profitbricks-licence-type"))
+	d.HotPlug = flags.StringSlice("profitbricks-hot-plug")
+	d.PrivateKeyPath = flags.String("profitbricks-ssh-key-path")
+	d.CloneFrom = flags.String("profitbricks-clone-from")
+	d.ImagePassword = flags.String("profitbricks-image-password")
+	d.GenerateImagePassword = flags.Bool("profitbricks-generate-image-password")
+	d.BootCdrom = flags.Bool("profitbricks-boot-cdrom")
+	d.ImageMatch = flags.String("profitbricks-image-match")
+	d.ImageNewest = flags.Bool("profitbricks-image-newest")
+	d.CacheTTL = flags.Int("profitbricks-cache-ttl")
+	d.RefreshCache = flags.Bool("profitbricks-refresh-cache")
+	d.DryRun = flags.Bool("profitbricks-dry-run")
+	d.StopGracePeriod = flags.Int("profitbricks-stop-grace-period")
+	d.SSHUser = flags.String("profitbricks-ssh-user")
 	d.SetSwarmConfigFromFlags(flags)
 
 	if d.URL == "" {
@@ -169,25 +300,45 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 }
 
 func (d *Driver) PreCreateCheck() error {
-	if d.Username == "" {
-		return fmt.Errorf("Please provide username as paramter --profitbricks-username or as environment variable $PROFITBRICKS_USERNAME")
+	errs := d.checkOptions()
+	if d.Username == "" || d.Password == "" {
+		return mcnutils.MultiError{Errs: errs}
+	}
+
+	var source *profitbricks.Server
+	if d.CloneFrom != "" {
+		if _, server, err := d.prepareClone(); err != nil {
+			errs = append(errs, err)
+		} else {
+			source = &server
+		}
+	} else {
+		errs = append(errs, d.checkImage()...)
 	}
+	errs = append(errs, d.checkQuota(d.newMachineUsage(source))...)
+
 	if d.DatacenterId != "" {
 		d.setPB()
 
 		dc := profitbricks.GetDatacenter(d.DatacenterId)
 
 		if dc.StatusCode == 404 {
-			return fmt.Errorf("DataCenter UUID %s does not exist.", d.DatacenterId)
+			errs = append(errs, fmt.Errorf("DataCenter UUID %s does not exist.", d.DatacenterId))
+		} else if dc.StatusCode > 299 {
+			errs = append(errs, fmt.Errorf("Error occurred while fetching datacenter %s: %s", d.DatacenterId, dc.Response))
+		} else if dc.Properties.Location != d.Location {
+			errs = append(errs, fmt.Errorf("Datacenter %s is in location %s, not %s", dc.Properties.Name, dc.Properties.Location, d.Location))
 		} else {
 			log.Info("Creating machine under " + dc.Properties.Name + " datacenter.")
 		}
 	}
 
-	if d.getImageId(d.Image) == "" {
-		return fmt.Errorf("The image/alias  %s %s %s", d.Image, d.Location, "does not exist.")
+	if len(errs) > 0 {
+		return mcnutils.MultiError{Errs: errs}
+	}
+	if d.DryRun {
+		return d.printPlan()
 	}
-
 	return nil
 }
 
@@ -196,29 +347,22 @@ func (d *Driver) Create() error {
 	d.setPB()
 
 	var err error
-	var image string
-	var alias string
-	if d.SSHKey == "" {
-		d.SSHKey, err = d.createSSHKey()
+	var volumes []profitbricks.Volume
+	if d.CloneFrom != "" {
+		var snapshotIds []string
+		volumes, snapshotIds, err = d.snapshotCloneSource()
+		defer d.deleteSnapshots(snapshotIds)
 		if err != nil {
 			return err
 		}
-	}
-	var result = d.getImageId(d.Image)
-	if !d.UseAlias {
-		image = result
 	} else {
-		alias = result
-	}
-
-	ipblockreq := profitbricks.IpBlock{
-		Properties: profitbricks.IpBlockProperties{
-			Size:     1,
-			Location: d.Location,
-		},
+		volumes, err = d.imageVolumes()
+		if err != nil {
+			return err
+		}
 	}
 
-	ipblockresp := profitbricks.ReserveIpBlock(ipblockreq)
+	ipblockresp := profitbricks.ReserveIpBlock(d.ipBlockSpec())
 
 	if ipblockresp.StatusCode > 299 {
 		return fmt.Errorf("An error occurred while reserving an ipblock: %s", ipblockresp.Response)
@@ -233,14 +377,7 @@ func (d *Driver) Create() error {
 
 	if d.DatacenterId == "" {
 		d.DCExists = false
-		dc = profitbricks.Datacenter{
-			Properties: profitbricks.DatacenterProperties{
-				Name:     d.MachineName,
-				Location: d.Location,
-			},
-		}
-
-		dc = profitbricks.CompositeCreateDatacenter(dc)
+		dc = profitbricks.CompositeCreateDatacenter(d.datacenterSpec())
 		if dc.StatusCode == 202 {
 			log.Info("Datacenter Created")
 		} else {
@@ -256,12 +393,7 @@ func (d *Driver) Create() error {
 		dc = profitbricks.GetDatacenter(d.DatacenterId)
 	}
 
-	lan := profitbricks.CreateLan(dc.Id, profitbricks.CreateLanRequest{
-		Properties: profitbricks.CreateLanProperties{
-			Public: true,
-			Name:   d.MachineName,
-		},
-	})
+	lan := profitbricks.CreateLan(dc.Id, d.lanSpec())
 
 	if lan.StatusCode == 202 {
 		log.Info("LAN Created")
@@ -281,48 +413,7 @@ func (d *Driver) Create() error {
 	lanId, _ := strconv.Atoi(lan.Id)
 
 	d.LanId = lan.Id
-	server := profitbricks.Server{
-		Properties: profitbricks.ServerProperties{
-			Name:             d.MachineName,
-			Ram:              d.Ram,
-			Cores:            d.Cores,
-			CpuFamily:        d.CpuFamily,
-			AvailabilityZone: d.ServerAvailabilityZone,
-		},
-		Entities: &profitbricks.ServerEntities{
-			Volumes: &profitbricks.Volumes{
-				Items: []profitbricks.Volume{
-					{
-						Properties: profitbricks.VolumeProperties{
-							Type:             d.DiskType,
-							Size:             d.DiskSize,
-							Name:             d.MachineName,
-							Image:            image,
-							ImageAlias:       alias,
-							SshKeys:          []string{d.SSHKey},
-							AvailabilityZone: d.VolumeAvailabilityZone,
-						},
-					},
-				},
-			},
-		},
-	}
-
-	nic := profitbricks.Nic{
-		Properties: &profitbricks.NicProperties{
-			Name: d.MachineName,
-			Lan:  lanId,
-			Ips:  ipblockresp.Properties.Ips,
-			Dhcp: true,
-		},
-	}
-
-	server.Entities.Nics = &profitbricks.Nics{
-		Items: []profitbricks.Nic{
-			nic,
-		},
-	}
-
+	server := d.serverSpec(volumes, lanId, ipblockresp.Properties.Ips)
 	server = profitbricks.CreateServer(dc.Id, server)
 
 	if server.StatusCode == 202 {
@@ -337,102 +428,164 @@ func (d *Driver) Create() error {
 		return err
 	}
 	d.ServerId = server.Id
+	if nic := d.machineNic(server); nic != nil {
+		d.NicId = nic.Id
+	}
 
 	d.IPAddress = ipblockresp.Properties.Ips[0]
 	log.Info(d.IPAddress)
+
+	if d.BootCdrom {
+		if err := d.attachBootCdrom(); err != nil {
+			return err
+		}
+	}
+
+	if d.CloneFrom != "" {
+		return d.rotateIdentity()
+	}
 	return nil
 }
 
-func (d *Driver) Restart() error {
-	d.setPB()
-	resp := profitbricks.RebootServer(d.DatacenterId, d.ServerId)
-	if resp.StatusCode != 202 {
-		return errors.New(string(resp.Body))
+func (d *Driver) ipBlockSpec() profitbricks.IpBlock {
+	return profitbricks.IpBlock{
+		Properties: profitbricks.IpBlockProperties{
+			Size:     1,
+			Location: d.Location,
+		},
 	}
-	return nil
 }
 
-func (d *Driver) Remove() error {
-	d.setPB()
+func (d *Driver) datacenterSpec() profitbricks.Datacenter {
+	return profitbricks.Datacenter{
+		Properties: profitbricks.DatacenterProperties{
+			Name:     d.MachineName,
+			Location: d.Location,
+		},
+	}
+}
+
+func (d *Driver) lanSpec() profitbricks.CreateLanRequest {
+	return profitbricks.CreateLanRequest{
+		Properties: profitbricks.CreateLanProperties{
+			Public: true,
+			Name:   d.MachineName,
+		},
+	}
+}
+
+// serverSpec returns the server to create with its volumes and a NIC in the LAN.
+func (d *Driver) serverSpec(volumes []profitbricks.Volume, lanId int, ips []string) profitbricks.Server {
+	return profitbricks.Server{
+		Properties: profitbricks.ServerProperties{
+			Name:             d.MachineName,
+			Ram:              d.Ram,
+			Cores:            d.Cores,
+			CpuFamily:        d.CpuFamily,
+			AvailabilityZone: d.ServerAvailabilityZone,
+			BootCdrom:        d.bootCdromReference(),
+		},
+		Entities: &profitbricks.ServerEntities{
+			Volumes: &profitbricks.Volumes{
+				Items: volumes,
+			},
+			Nics: &profitbricks.Nics{
+				Items: []profitbricks.Nic{
+					{
+						Properties: &profitbricks.NicProperties{
+							Name: d.MachineName,
+							Lan:  lanId,
+							Ips:  ips,
+							Dhcp: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
 
-	if !d.DCExists {
-		servers := profitbricks.ListServers(d.DatacenterId)
-		if len(servers.Items) == 1 {
-			resp := profitbricks.DeleteDatacenter(d.DatacenterId)
-			if resp.StatusCode > 299 {
-				return errors.New(string(resp.Body))
-			}
-
-			err := d.waitTillProvisioned(strings.Join(resp.Headers["Location"], ""))
-			if err != nil {
-				return err
-			}
+// imageVolumes returns the boot volume to create from the configured image.
+func (d *Driver) imageVolumes() ([]profitbricks.Volume, error) {
+	var err error
+	if d.SSHKey == "" {
+		if d.PrivateKeyPath != "" {
+			d.SSHKey, err = d.copySSHKey(d.PrivateKeyPath)
 		} else {
-			err := d.removeServer(d.DatacenterId, d.ServerId, d.LanId)
-			if err != nil {
-				return err
-			}
+			d.SSHKey, err = d.createSSHKey()
 		}
-	} else {
-		err := d.removeServer(d.DatacenterId, d.ServerId, d.LanId)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-
-	ipblocks := profitbricks.ListIpBlocks()
-
-	for _, i := range ipblocks.Items {
-		for _, v := range i.Properties.Ips {
-			if d.IPAddress == v {
-				resp := profitbricks.ReleaseIpBlock(i.Id)
-				if resp.StatusCode > 299 {
-					return errors.New(string(resp.Body))
-				}
-			}
+	var result = d.ImageId
+	if result == "" {
+		if result, err = d.findImage(d.Image); err != nil {
+			return nil, err
 		}
 	}
-
-	return nil
-}
-
-func (d *Driver) removeServer(datacenterId string, serverId string, lanId string) error {
-	server := profitbricks.GetServer(datacenterId, serverId)
-
-	if server.StatusCode > 299 {
-		return errors.New(server.Response)
+	if d.BootCdrom {
+		return d.cdromVolumes(), nil
 	}
-
-	if server.Entities != nil && server.Entities.Volumes != nil && len(server.Entities.Volumes.Items) > 0 {
-		volumeId := server.Entities.Volumes.Items[0].Id
-		resp := profitbricks.DeleteVolume(d.DatacenterId, volumeId)
-		if resp.StatusCode > 299 {
-			return errors.New(string(resp.Body))
+	if d.GenerateImagePassword && d.ImagePassword == "" {
+		if d.ImagePassword, err = generateImagePassword(); err != nil {
+			return nil, err
 		}
-		err := d.waitTillProvisioned(resp.Headers.Get("Location"))
-
-		if err != nil {
-			return err
+		if err := d.saveConsolePassword(); err != nil {
+			return nil, err
 		}
 	}
-	resp := profitbricks.DeleteServer(datacenterId, serverId)
-	if resp.StatusCode > 299 {
-		return errors.New(string(resp.Body))
+
+	return []profitbricks.Volume{d.imageVolume(result, d.SSHKey, d.ImagePassword)}, nil
+}
+
+// imageVolume returns the boot volume to create from the resolved image or alias.
+func (d *Driver) imageVolume(result, sshKey, imagePassword string) profitbricks.Volume {
+	var image string
+	var alias string
+	if !d.UseAlias {
+		image = result
+	} else {
+		alias = result
 	}
 
-	err := d.waitTillProvisioned(strings.Join(resp.Headers["Location"], ""))
-	if err != nil {
-		return err
+	// SSH keys and passwords can only be injected into public images
+	var sshKeys []string
+	var password string
+	if !d.UseSnapshot {
+		sshKeys = []string{sshKey}
+		password = imagePassword
 	}
 
-	resp = profitbricks.DeleteLan(datacenterId, lanId)
-	if resp.StatusCode > 299 {
-		return errors.New(string(resp.Body))
+	volume := profitbricks.Volume{
+		Properties: profitbricks.VolumeProperties{
+			Type:             d.DiskType,
+			Size:             d.DiskSize,
+			Name:             d.MachineName,
+			Image:            image,
+			ImageAlias:       alias,
+			SshKeys:          sshKeys,
+			ImagePassword:    password,
+			AvailabilityZone: d.VolumeAvailabilityZone,
+			Bus:              d.VolumeBus,
+			LicenceType:      d.LicenceType,
+		},
 	}
+	setHotPlug(&volume.Properties, d.HotPlug)
+	return volume
+}
 
-	err = d.waitTillProvisioned(strings.Join(resp.Headers["Location"], ""))
-	if err != nil {
-		return err
+func (d *Driver) Restart() error {
+	d.setPB()
+	resp := profitbricks.RebootServer(d.DatacenterId, d.ServerId)
+	if resp.StatusCode != 202 {
+		return &OperationError{Operation: "restart", Err: errors.New(string(resp.Body))}
+	}
+	if err := d.waitTillProvisioned(resp.Headers.Get("Location")); err != nil {
+		return &OperationError{Operation: "restart", Err: err}
+	}
+	if err := d.waitForVmState("RUNNING"); err != nil {
+		return &OperationError{Operation: "restart", Err: err}
 	}
 	return nil
 }
@@ -450,15 +603,21 @@ func (d *Driver) GetURL() (string, error) {
 
 func (d *Driver) Start() error {
 	serverstate, err := d.GetState()
-	d.setPB()
-
 	if err != nil {
 		return err
 	}
-	if serverstate != state.Running {
-		profitbricks.StartServer(d.DatacenterId, d.ServerId)
-	} else {
-		log.Info("Host is already running or starting")
+
+	switch serverstate {
+	case state.Running:
+		log.Info("Host is already running")
+	case state.Starting:
+		log.Info("Host is already starting")
+		err = d.waitForVmState("RUNNING")
+	default:
+		err = d.powerOn()
+	}
+	if err != nil {
+		return &OperationError{Operation: "start", Err: err}
 	}
 	return nil
 }
@@ -468,71 +627,66 @@ func (d *Driver) Stop() error {
 	if err != nil {
 		return err
 	}
-	if vmstate == state.Stopped {
+
+	switch vmstate {
+	case state.Stopped:
 		log.Infof("Host is already stopped")
-		return nil
+	case state.Stopping:
+		log.Info("Host may already be stopping, waiting for it")
+		err = d.waitForVmStateWithin("SHUTOFF", stoppingWaitCount)
+		if _, ok := err.(*StateTimeoutError); ok {
+			log.Info("Host did not stop, stopping it")
+			err = d.shutdown()
+		}
+	default:
+		err = d.shutdown()
 	}
-
-	d.setPB()
-	profitbricks.StopServer(d.DatacenterId, d.ServerId)
 	if err != nil {
-		return err
+		return &OperationError{Operation: "stop", Err: err}
 	}
 	return nil
 }
 
 func (d *Driver) Kill() error {
-	resp := profitbricks.StopServer(d.DatacenterId, d.ServerId)
-	if resp.StatusCode != 202 {
-		return errors.New(string(resp.Body))
+	if err := d.powerOff(); err != nil {
+		return &OperationError{Operation: "kill", Err: err}
 	}
 	return nil
 }
 
 func (d *Driver) GetIP() (string, error) {
-	d.setPB()
-	server := profitbricks.GetServer(d.DatacenterId, d.ServerId)
+	server, err := d.getServer()
+	if err != nil {
+		if _, ok := err.(*UnreachableError); ok && d.IPAddress != "" {
+			log.Warnf("Using the cached IP address %s: %s", d.IPAddress, err)
+			return d.IPAddress, nil
+		}
+		return "", err
+	}
 
-	d.IPAddress = server.Entities.Nics.Items[0].Properties.Ips[0]
-	if d.IPAddress == "" {
-		return "", fmt.Errorf("IP address is not set")
+	nic := d.machineNic(server)
+	if nic == nil || nic.Properties == nil || len(nic.Properties.Ips) == 0 || nic.Properties.Ips[0] == "" {
+		return "", &NoIPError{ServerId: d.ServerId}
 	}
+	d.NicId = nic.Id
+	d.IPAddress = nic.Properties.Ips[0]
 	return d.IPAddress, nil
 }
 
 func (d *Driver) GetState() (state.State, error) {
-	d.setPB()
-	server := profitbricks.GetServer(d.DatacenterId, d.ServerId)
-
-	if server.StatusCode > 299 {
-
-		if server.StatusCode == 401 {
-			return state.None, fmt.Errorf("Unauthorized. Either user name or password are incorrect.")
-
-		} else {
-			return state.None, fmt.Errorf("Error occurred while fetching a server: %s", server.Response)
+	server, err := d.getServer()
+	if err != nil {
+		if _, ok := err.(*NotFoundError); ok {
+			return state.Error, err
 		}
+		return state.None, err
 	}
 
-	switch server.Metadata.State {
-	case "NOSTATE":
-		return state.None, nil
-	case "AVAILABLE":
-		return state.Running, nil
-	case "PAUSED":
-		return state.Paused, nil
-	case "BLOCKED":
-		return state.Stopped, nil
-	case "SHUTDOWN":
-		return state.Stopped, nil
-	case "SHUTOFF":
-		return state.Stopped, nil
-	case "CHRASHED":
-		return state.Error, nil
-	case "INACTIVE":
-		return state.Stopped, nil
-	}
-	return state.None, nil
+	var metadataState string
+	if server.Metadata != nil {
+		metadataState = server.Metadata.State
+	}
+	return machineState(server.Properties.VmState, metadataState), nil
 }
 
 //Private helper functions
@@ -559,6 +713,22 @@ func (d *Driver) createSSHKey() (string, error) {
 	return string(publicKey), nil
 }
 
+// copySSHKey copies an existing key pair into the machine's store.
+func (d *Driver) copySSHKey(path string) (string, error) {
+	if err := mcnutils.CopyFile(path, d.GetSSHKeyPath()); err != nil {
+		return "", err
+	}
+	if err := mcnutils.CopyFile(path+".pub", d.publicSSHKeyPath()); err != nil {
+		return "", err
+	}
+
+	publicKey, err := ioutil.ReadFile(d.publicSSHKeyPath())
+	if err != nil {
+		return "", err
+	}
+	return string(publicKey), nil
+}
+
 func (d *Driver) isSwarmMaster() bool {
 	return d.SwarmMaster
 }
@@ -580,39 +750,53 @@ func (d *Driver) waitTillProvisioned(path string) error {
 	return errors.New("Timeout has expired.")
 }
 
-func (d *Driver) getImageId(imageName string) string {
-	d.setPB()
-	d.UseAlias = false
-	//first look if the provided parameter matches an alias, if a match is found we return the image alias
-	location := profitbricks.GetLocation(d.Location)
-
-	for _, alias := range location.Properties.ImageAliases {
-		if alias == imageName {
-			d.UseAlias = true
-			return imageName
+func (d *Driver) waitForVmState(vmState string) error {
+	return d.waitForVmStateWithin(vmState, stateWaitCount)
+}
+
+func (d *Driver) waitForVmStateWithin(vmState string, attempts int) error {
+	var current string
+	var apiErr error
+	err := mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		server, err := d.getServer()
+		if err != nil {
+			apiErr = err
+			return false, apiErr
 		}
+		current = server.Properties.VmState
+		return current == vmState, nil
+	}, attempts, stateWaitInterval)
+	if apiErr != nil {
+		return apiErr
 	}
+	if err != nil {
+		return &StateTimeoutError{Expected: vmState, Current: current}
+	}
+	return nil
+}
 
-	//if no alias matchs we do extended search and return the image id
-	images := profitbricks.ListImages()
-
-	if images.StatusCode == 401 {
-		log.Error("Authentication failed")
-		return ""
+// powerOff stops the server through the API and waits until it is shut off.
+func (d *Driver) powerOff() error {
+	d.setPB()
+	resp := profitbricks.StopServer(d.DatacenterId, d.ServerId)
+	if resp.StatusCode != 202 {
+		return errors.New(string(resp.Body))
 	}
+	if err := d.waitTillProvisioned(resp.Headers.Get("Location")); err != nil {
+		return err
+	}
+	return d.waitForVmState("SHUTOFF")
+}
 
-	for _, image := range images.Items {
-		imgName := ""
-		if image.Properties.Name != "" {
-			imgName = image.Properties.Name
-		}
-		diskType := d.DiskType
-		if d.DiskType == "SSD" {
-			diskType = "HDD"
-		}
-		if imgName != "" && strings.Contains(strings.ToLower(imgName), strings.ToLower(imageName)) && image.Properties.ImageType == diskType && image.Properties.Location == d.Location {
-			return image.Id
-		}
+// powerOn starts the server through the API and waits until it is running.
+func (d *Driver) powerOn() error {
+	d.setPB()
+	resp := profitbricks.StartServer(d.DatacenterId, d.ServerId)
+	if resp.StatusCode != 202 {
+		return errors.New(string(resp.Body))
+	}
+	if err := d.waitTillProvisioned(resp.Headers.Get("Location")); err != nil {
+		return err
 	}
-	return ""
+	return d.waitForVmState("RUNNING")
 }
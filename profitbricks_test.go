@@ -64,6 +64,22 @@ func getDefaultTestDriverFlags() *DriverOptionsMock {
 			"profitbricks-volume-availability-zone": "AUTO",
 			"profitbricks-server-availability-zone": "AUTO",
 			"profitbricks-ssh-key":                  ``,
+			"profitbricks-volume-bus":               "VIRTIO",
+			"profitbricks-licence-type":             "",
+			"profitbricks-hot-plug":                 []string{},
+			"profitbricks-ssh-key-path":             "",
+			"profitbricks-clone-from":               "",
+			"profitbricks-image-password":           "",
+			"profitbricks-generate-image-password":  false,
+			"profitbricks-boot-cdrom":               false,
+			"profitbricks-ssh-user":                 "root",
+			"profitbricks-image-match":              "substring",
+			"profitbricks-image-type":               "HDD",
+			"profitbricks-cache-ttl":                3600,
+			"profitbricks-refresh-cache":            false,
+			"profitbricks-dry-run":                  false,
+			"profitbricks-stop-grace-period":        60,
+			"profitbricks-image-newest":             false,
 			"swarm-master":                          true,
 			"swarm-host":                            "2",
 			"swarm-discovery":                       "3",
@@ -126,7 +142,7 @@ func TestRemove(t *testing.T) {
 
 func TestGetImageName(t *testing.T) {
 	d, _ := getTestDriver()
-	res := d.getImageId("Debian-8-server1")
+	res, _ := d.findImage("Debian-8-server1")
 
 	fmt.Println(res == "")
 }
@@ -0,0 +1,112 @@
+package profitbricks
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// machineUsage is what a new machine takes from the contract's resources.
+type machineUsage struct {
+	cores   int
+	ram     int
+	volumes []profitbricks.VolumeProperties
+	ips     int
+}
+
+// newMachineUsage returns the usage of the machine to create. A clone gets a volume
+// for every volume of its source server.
+func (d *Driver) newMachineUsage(source *profitbricks.Server) machineUsage {
+	usage := machineUsage{cores: d.Cores, ram: d.Ram, ips: 1}
+	if source != nil && source.Entities != nil && source.Entities.Volumes != nil {
+		for _, volume := range source.Entities.Volumes.Items {
+			usage.volumes = append(usage.volumes, volume.Properties)
+		}
+	} else {
+		usage.volumes = []profitbricks.VolumeProperties{{Size: d.DiskSize, Type: d.DiskType}}
+	}
+	return usage
+}
+
+// checkQuota validates the machine's usage against the contract's resource limits. A
+// contract whose resources cannot be read is not checked, since sub-users may lack
+// access to them.
+func (d *Driver) checkQuota(usage machineUsage) []error {
+	d.setPB()
+	resources := profitbricks.GetContractResources()
+	if resources.StatusCode > 299 {
+		log.Warnf("Skipping the contract quota check: %s", resources.Response)
+		return nil
+	}
+	return checkContractLimits(resources.Properties.ResourceLimits, usage)
+}
+
+// checkContractLimits validates the usage against the per-server, per-volume and
+// remaining per-contract limits.
+func checkContractLimits(limits *profitbricks.ResourcesLimits, usage machineUsage) []error {
+	if limits == nil {
+		log.Debug("Contract has no resource limits")
+		return nil
+	}
+	return append(checkServerLimits(limits, usage.cores, usage.ram), checkRemainingLimits(limits, usage)...)
+}
+
+// checkServerLimits validates the size of a server against the per-server limits.
+func checkServerLimits(limits *profitbricks.ResourcesLimits, cores, ram int) []error {
+	var errs []error
+	if limits.CoresPerServer > 0 && cores > int(limits.CoresPerServer) {
+		errs = append(errs, fmt.Errorf("%d cores exceed the contract limit of %d cores per server", cores, limits.CoresPerServer))
+	}
+	if limits.RamPerServer > 0 && ram > int(limits.RamPerServer) {
+		errs = append(errs, fmt.Errorf("%d MB RAM exceed the contract limit of %d MB per server", ram, limits.RamPerServer))
+	}
+	return errs
+}
+
+// checkRemainingLimits validates the resources the usage adds to the contract against
+// the per-volume limits and what remains in the contract.
+func checkRemainingLimits(limits *profitbricks.ResourcesLimits, usage machineUsage) []error {
+	var errs []error
+	if limits.CoresPerContract > 0 && usage.cores > 0 {
+		if remaining := int(limits.CoresPerContract - limits.CoresProvisioned); usage.cores > remaining {
+			errs = append(errs, fmt.Errorf("Machine needs %d more cores, %d remaining in contract", usage.cores, remaining))
+		}
+	}
+	if limits.RamPerContract > 0 && usage.ram > 0 {
+		if remaining := int(limits.RamPerContract - limits.RamProvisioned); usage.ram > remaining {
+			errs = append(errs, fmt.Errorf("Machine needs %d MB more RAM, %d MB remaining in contract", usage.ram, remaining))
+		}
+	}
+
+	var hdd, ssd int64
+	for _, volume := range usage.volumes {
+		perVolume := limits.HddLimitPerVolume
+		if volume.Type == "SSD" {
+			perVolume = limits.SsdLimitPerVolume
+			ssd += int64(volume.Size)
+		} else {
+			hdd += int64(volume.Size)
+		}
+		if perVolume > 0 && int64(volume.Size) > perVolume {
+			errs = append(errs, fmt.Errorf("%d GB %s volume exceeds the contract limit of %d GB per volume", volume.Size, volume.Type, perVolume))
+		}
+	}
+	if limits.HddLimitPerContract > 0 && hdd > 0 {
+		if remaining := limits.HddLimitPerContract - limits.HddVolumeProvisioned; hdd > remaining {
+			errs = append(errs, fmt.Errorf("Machine needs %d GB HDD storage, %d GB remaining in contract", hdd, remaining))
+		}
+	}
+	if limits.SsdLimitPerContract > 0 && ssd > 0 {
+		if remaining := limits.SsdLimitPerContract - limits.SsdVolumeProvisioned; ssd > remaining {
+			errs = append(errs, fmt.Errorf("Machine needs %d GB SSD storage, %d GB remaining in contract", ssd, remaining))
+		}
+	}
+
+	if limits.ReservableIps > 0 {
+		if remaining := int(limits.ReservableIps - limits.ReservedIpsOnContract); usage.ips > remaining {
+			errs = append(errs, fmt.Errorf("Machine needs %d reserved IP, %d remaining in contract", usage.ips, remaining))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,51 @@
+package profitbricks
+
+import (
+	"testing"
+
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+func TestCheckContractLimits(t *testing.T) {
+	limits := &profitbricks.ResourcesLimits{
+		CoresPerServer:        8,
+		CoresPerContract:      10,
+		CoresProvisioned:      8,
+		RamPerServer:          16384,
+		RamPerContract:        32768,
+		RamProvisioned:        16384,
+		HddLimitPerVolume:     500,
+		HddLimitPerContract:   1000,
+		HddVolumeProvisioned:  900,
+		SsdLimitPerVolume:     200,
+		SsdLimitPerContract:   400,
+		SsdVolumeProvisioned:  0,
+		ReservableIps:         5,
+		ReservedIpsOnContract: 4,
+	}
+
+	hdd := func(size int) []profitbricks.VolumeProperties {
+		return []profitbricks.VolumeProperties{{Size: size, Type: "HDD"}}
+	}
+	tests := []struct {
+		name   string
+		usage  machineUsage
+		errors int
+	}{
+		{"fits", machineUsage{cores: 2, ram: 4096, volumes: hdd(50), ips: 1}, 0},
+		{"too many cores", machineUsage{cores: 4, ram: 4096, volumes: hdd(50), ips: 1}, 1},
+		{"HDD storage used up", machineUsage{cores: 2, ram: 4096, volumes: hdd(200), ips: 1}, 1},
+		{"SSD volume too big", machineUsage{cores: 2, ram: 4096, volumes: []profitbricks.VolumeProperties{{Size: 300, Type: "SSD"}}, ips: 1}, 1},
+		{"everything", machineUsage{cores: 12, ram: 32768, volumes: hdd(600), ips: 2}, 7},
+	}
+
+	for _, test := range tests {
+		if errs := checkContractLimits(limits, test.usage); len(errs) != test.errors {
+			t.Errorf("%s: expected %d errors, got %v", test.name, test.errors, errs)
+		}
+	}
+
+	if errs := checkContractLimits(nil, machineUsage{cores: 100}); len(errs) != 0 {
+		t.Errorf("Expected no errors without limits, got %v", errs)
+	}
+}
@@ -0,0 +1,218 @@
+package profitbricks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// removal collects what a Remove found already gone and the errors of its steps, so a
+// failed step does not keep the independent ones from running.
+type removal struct {
+	missing []string
+	errs    []error
+}
+
+// do runs one step of the removal and reports whether the resource is gone. Resources
+// that no longer exist count as removed, other errors are collected. The SDK panics
+// when the API cannot be reached, the panic is collected as an UnreachableError.
+func (r *removal) do(step func() error) (gone bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.errs = append(r.errs, &UnreachableError{Err: fmt.Errorf("%v", p)})
+			gone = false
+		}
+	}()
+
+	err := step()
+	if e, ok := err.(*NotFoundError); ok {
+		r.missing = append(r.missing, e.Resource+" "+e.Id)
+		return true
+	}
+	if err != nil {
+		r.errs = append(r.errs, err)
+		return false
+	}
+	return true
+}
+
+// Remove deletes the resources of the machine. Every step that does not depend on a
+// failed one is attempted, and resources that are already gone are skipped, so running
+// Remove again after a partial failure removes what is left.
+func (d *Driver) Remove() error {
+	d.setPB()
+	r := &removal{}
+
+	if d.DatacenterId != "" && !d.removeDatacenter(r) {
+		if d.removeServer(r) {
+			d.removeLan(r)
+		}
+	}
+	d.releaseIpBlock(r)
+
+	if len(r.missing) > 0 {
+		log.Warnf("Skipped resources that were already removed: %s", strings.Join(r.missing, ", "))
+	}
+	if len(r.errs) > 0 {
+		return mcnutils.MultiError{Errs: r.errs}
+	}
+	return nil
+}
+
+// removeDatacenter deletes the datacenter created for the machine when no other
+// servers are left in it, and reports whether the datacenter is gone.
+func (d *Driver) removeDatacenter(r *removal) bool {
+	if d.DCExists {
+		return false
+	}
+
+	gone, empty := false, false
+	if !r.do(func() error {
+		servers := profitbricks.ListServers(d.DatacenterId)
+		if servers.StatusCode == 404 {
+			gone = true
+			return &NotFoundError{Resource: "Datacenter", Id: d.DatacenterId}
+		}
+		if servers.StatusCode > 299 {
+			return fmt.Errorf("Error occurred while listing servers: %s", servers.Response)
+		}
+		empty = onlyServer(servers.Items, d.ServerId)
+		return nil
+	}) {
+		return false
+	}
+	if gone {
+		return true
+	}
+	if !empty {
+		return false
+	}
+
+	return r.do(func() error {
+		return d.deleted("Datacenter", d.DatacenterId, profitbricks.DeleteDatacenter(d.DatacenterId))
+	})
+}
+
+// removeServer deletes the volumes the driver created for the server, then the
+// server, and reports whether the server is gone. The server is kept when a volume
+// cannot be deleted, so the volume is still found on the next attempt. Volumes attached
+// to the server outside docker-machine are left in place.
+func (d *Driver) removeServer(r *removal) bool {
+	if d.ServerId == "" {
+		return true
+	}
+
+	var server profitbricks.Server
+	found := false
+	if !r.do(func() (err error) {
+		server, err = d.getServer()
+		found = err == nil
+		return err
+	}) {
+		return false
+	}
+	if !found {
+		return true
+	}
+
+	volumesGone := true
+	boot := bootVolume(server)
+	if boot != nil {
+		for _, volume := range server.Entities.Volumes.Items {
+			if volume.Id != boot.Id && !d.isCloneVolume(volume) {
+				log.Warnf("Keeping volume %s (%s), it was not created by docker-machine", volume.Properties.Name, volume.Id)
+				continue
+			}
+			id := volume.Id
+			if !r.do(func() error {
+				return d.deleted("Volume", id, profitbricks.DeleteVolume(d.DatacenterId, id))
+			}) {
+				volumesGone = false
+			}
+		}
+	}
+	if !volumesGone {
+		r.errs = append(r.errs, fmt.Errorf("Server %s was kept because not all of its volumes could be deleted", d.ServerId))
+		return false
+	}
+
+	return r.do(func() error {
+		return d.deleted("Server", d.ServerId, profitbricks.DeleteServer(d.DatacenterId, d.ServerId))
+	})
+}
+
+// isCloneVolume reports whether the volume is named like the volumes cloneVolume
+// creates, the machine name or the machine name followed by -N.
+func (d *Driver) isCloneVolume(volume profitbricks.Volume) bool {
+	name := volume.Properties.Name
+	if name == d.MachineName {
+		return true
+	}
+	suffix := strings.TrimPrefix(name, d.MachineName+"-")
+	if suffix == name || suffix == "" {
+		return false
+	}
+	for _, c := range suffix {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *Driver) removeLan(r *removal) {
+	if d.LanId == "" {
+		return
+	}
+	r.do(func() error {
+		return d.deleted("LAN", d.LanId, profitbricks.DeleteLan(d.DatacenterId, d.LanId))
+	})
+}
+
+// releaseIpBlock releases the IP block reserved for the machine's IP address.
+func (d *Driver) releaseIpBlock(r *removal) {
+	if d.IPAddress == "" {
+		return
+	}
+	r.do(func() error {
+		ipblocks := profitbricks.ListIpBlocks()
+		if ipblocks.StatusCode > 299 {
+			return fmt.Errorf("Error occurred while listing IP blocks: %s", ipblocks.Response)
+		}
+		for _, ipblock := range ipblocks.Items {
+			for _, ip := range ipblock.Properties.Ips {
+				if ip == d.IPAddress {
+					return d.deleted("IP block", ipblock.Id, profitbricks.ReleaseIpBlock(ipblock.Id))
+				}
+			}
+		}
+		return &NotFoundError{Resource: "IP block of", Id: d.IPAddress}
+	})
+}
+
+// deleted checks the response of a delete request and waits for it to finish. A
+// resource that no longer exists is returned as a NotFoundError.
+func (d *Driver) deleted(resource, id string, resp profitbricks.Resp) error {
+	if resp.StatusCode == 404 {
+		return &NotFoundError{Resource: resource, Id: id}
+	}
+	if resp.StatusCode > 299 {
+		return fmt.Errorf("Error occurred while deleting %s %s: %s", resource, id, resp.Body)
+	}
+	return d.waitTillProvisioned(resp.Headers.Get("Location"))
+}
+
+// onlyServer reports whether the machine's server is the only server left in the
+// datacenter, or the datacenter has no servers at all.
+func onlyServer(servers []profitbricks.Server, serverId string) bool {
+	switch len(servers) {
+	case 0:
+		return true
+	case 1:
+		return servers[0].Id == serverId
+	}
+	return false
+}
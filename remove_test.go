@@ -0,0 +1,143 @@
+package profitbricks
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+func TestRemoveDeletedServer(t *testing.T) {
+	var deleted []string
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE" && r.URL.Path == "/datacenters/dc/lans/1":
+			deleted = append(deleted, r.URL.Path)
+			w.Header().Set("Location", "http://"+r.Host+"/requests/1/status")
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/requests/1/status":
+			w.Write([]byte(`{"metadata":{"status":"DONE"}}`))
+		case r.URL.Path == "/ipblocks":
+			w.Write([]byte(`{"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer done()
+
+	d.DCExists = true
+	d.LanId = "1"
+	d.IPAddress = "203.0.113.7"
+	if err := d.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("Expected the LAN to be deleted, deleted %v", deleted)
+	}
+}
+
+func TestRemoveEmptyDatacenter(t *testing.T) {
+	var deleted []string
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE":
+			deleted = append(deleted, r.URL.Path)
+			w.Header().Set("Location", "http://"+r.Host+"/requests/1/status")
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/requests/1/status":
+			w.Write([]byte(`{"metadata":{"status":"DONE"}}`))
+		case r.URL.Path == "/datacenters/dc/servers":
+			w.Write([]byte(`{"items":[]}`))
+		case r.URL.Path == "/ipblocks":
+			w.Write([]byte(`{"items":[{"id":"ipb","properties":{"ips":["203.0.113.7"]}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer done()
+
+	d.IPAddress = "203.0.113.7"
+	if err := d.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/datacenters/dc", "/ipblocks/ipb"}
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("Expected %v to be deleted, deleted %v", expected, deleted)
+	}
+}
+
+func TestRemoveConverges(t *testing.T) {
+	resources := map[string]bool{
+		"/datacenters/dc/volumes/vol-1": true,
+		"/datacenters/dc/volumes/vol-2": true,
+		"/datacenters/dc/volumes/vol-3": true,
+		"/datacenters/dc/servers/srv":   true,
+		"/datacenters/dc/lans/1":        true,
+		"/ipblocks/ipb":                 true,
+	}
+	names := map[string]string{"vol-1": "test", "vol-2": "test-1", "vol-3": "test-data"}
+	failing := map[string]bool{"/datacenters/dc/volumes/vol-2": true}
+
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/requests/1/status":
+			w.Write([]byte(`{"metadata":{"status":"DONE"}}`))
+		case r.Method == "DELETE" && failing[r.URL.Path]:
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.Method == "DELETE" && resources[r.URL.Path]:
+			delete(resources, r.URL.Path)
+			w.Header().Set("Location", "http://"+r.Host+"/requests/1/status")
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/datacenters/dc/servers/srv" && resources[r.URL.Path]:
+			var volumes []string
+			for _, id := range []string{"vol-1", "vol-2", "vol-3"} {
+				if resources["/datacenters/dc/volumes/"+id] {
+					volumes = append(volumes, `{"id":"`+id+`","properties":{"name":"`+names[id]+`"}}`)
+				}
+			}
+			w.Write([]byte(`{"id":"srv","entities":{"volumes":{"items":[` + strings.Join(volumes, ",") + `]}}}`))
+		case r.URL.Path == "/ipblocks":
+			if resources["/ipblocks/ipb"] {
+				w.Write([]byte(`{"items":[{"id":"ipb","properties":{"ips":["203.0.113.7"]}}]}`))
+			} else {
+				w.Write([]byte(`{"items":[]}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer done()
+
+	d.DCExists = true
+	d.LanId = "1"
+	d.IPAddress = "203.0.113.7"
+
+	err := d.Remove()
+	if multi, ok := err.(mcnutils.MultiError); !ok || len(multi.Errs) != 2 {
+		t.Fatalf("Expected the failed volume and the kept server as errors, got %v", err)
+	}
+	expected := map[string]bool{
+		"/datacenters/dc/volumes/vol-2": true,
+		"/datacenters/dc/volumes/vol-3": true,
+		"/datacenters/dc/servers/srv":   true,
+		"/datacenters/dc/lans/1":        true,
+	}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Errorf("Expected only %v to be left, got %v", expected, resources)
+	}
+
+	delete(failing, "/datacenters/dc/volumes/vol-2")
+	if err := d.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]bool{"/datacenters/dc/volumes/vol-3": true}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Errorf("Expected only the volume attached outside docker-machine to be left, got %v", resources)
+	}
+
+	if err := d.Remove(); err != nil {
+		t.Errorf("Expected removing a removed machine to succeed, got %s", err)
+	}
+}
@@ -0,0 +1,139 @@
+package profitbricks
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// Resize changes the cores, RAM (MB) and CPU family of the server, zero values keep
+// the current setting. The change is applied live when the boot volume supports
+// hot-plugging it, otherwise the server is stopped, patched and started again.
+func (d *Driver) Resize(cores, ram int, cpuFamily string) error {
+	server, err := d.getServer()
+	if err != nil {
+		return err
+	}
+
+	current := server.Properties
+	if cores == 0 {
+		cores = current.Cores
+	}
+	if ram == 0 {
+		ram = current.Ram
+	}
+	if cpuFamily == "" {
+		cpuFamily = current.CpuFamily
+	}
+
+	if cores == current.Cores && ram == current.Ram && cpuFamily == current.CpuFamily {
+		log.Infof("Server already has %d cores, %d MB RAM and CPU family %s", cores, ram, cpuFamily)
+		d.Cores, d.Ram, d.CpuFamily = cores, ram, cpuFamily
+		return nil
+	}
+
+	if ram%256 != 0 {
+		return fmt.Errorf("RAM must be a multiple of 256 MB, got %d MB", ram)
+	}
+
+	resources := profitbricks.GetContractResources()
+	if resources.StatusCode > 299 {
+		return fmt.Errorf("Error occurred while fetching contract resources: %s", resources.Response)
+	}
+	if err := checkResizeLimits(resources.Properties.ResourceLimits, current, cores, ram); err != nil {
+		return err
+	}
+
+	props := profitbricks.ServerProperties{Cores: cores, Ram: ram}
+	if cpuFamily != current.CpuFamily {
+		props.CpuFamily = cpuFamily
+	}
+
+	var volume profitbricks.VolumeProperties
+	if boot := bootVolume(server); boot != nil {
+		volume = boot.Properties
+	}
+
+	if canResizeLive(current, props, volume) {
+		log.Infof("Resizing server live to %d cores and %d MB RAM", cores, ram)
+		if err := d.patchServer(props); err != nil {
+			return err
+		}
+	} else {
+		wasRunning := current.VmState == "RUNNING"
+		if wasRunning {
+			log.Info("The boot volume does not support hot-plugging this change, stopping the server")
+			if err := d.shutdown(); err != nil {
+				return err
+			}
+		}
+		log.Infof("Resizing server to %d cores, %d MB RAM and CPU family %s", cores, ram, cpuFamily)
+		if err := d.patchServer(props); err != nil {
+			if wasRunning {
+				if startErr := d.powerOn(); startErr != nil {
+					log.Errorf("Error starting the server after a failed resize: %s", startErr)
+				}
+			}
+			return err
+		}
+		if wasRunning {
+			if err := d.powerOn(); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.Cores, d.Ram, d.CpuFamily = cores, ram, cpuFamily
+	return nil
+}
+
+func (d *Driver) patchServer(props profitbricks.ServerProperties) error {
+	server := profitbricks.PatchServer(d.DatacenterId, d.ServerId, props)
+	if server.StatusCode > 299 {
+		return errors.New("Error while updating the server " + server.Response)
+	}
+	return d.waitTillProvisioned(server.Headers.Get("Location"))
+}
+
+// canResizeLive reports whether the boot volume allows applying the change to a
+// running server.
+func canResizeLive(current, target profitbricks.ServerProperties, volume profitbricks.VolumeProperties) bool {
+	if current.VmState != "RUNNING" {
+		return false
+	}
+	if target.CpuFamily != "" && target.CpuFamily != current.CpuFamily {
+		return false
+	}
+	if (target.Cores > current.Cores && !volume.CpuHotPlug) || (target.Cores < current.Cores && !volume.CpuHotUnplug) {
+		return false
+	}
+	if (target.Ram > current.Ram && !volume.RamHotPlug) || (target.Ram < current.Ram && !volume.RamHotUnplug) {
+		return false
+	}
+	return true
+}
+
+// checkResizeLimits validates the new size against the contract's per-server limits
+// and the cores and RAM still available in the contract.
+func checkResizeLimits(limits *profitbricks.ResourcesLimits, current profitbricks.ServerProperties, cores, ram int) error {
+	if limits == nil {
+		log.Debug("Contract has no resource limits")
+		return nil
+	}
+
+	var extra machineUsage
+	if cores > current.Cores {
+		extra.cores = cores - current.Cores
+	}
+	if ram > current.Ram {
+		extra.ram = ram - current.Ram
+	}
+	errs := append(checkServerLimits(limits, cores, ram), checkRemainingLimits(limits, extra)...)
+	if len(errs) > 0 {
+		return mcnutils.MultiError{Errs: errs}
+	}
+	return nil
+}
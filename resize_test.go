@@ -0,0 +1,67 @@
+package profitbricks
+
+import (
+	"testing"
+
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+func TestCanResizeLive(t *testing.T) {
+	running := profitbricks.ServerProperties{Cores: 2, Ram: 2048, CpuFamily: "AMD_OPTERON", VmState: "RUNNING"}
+	hotPlug := profitbricks.VolumeProperties{CpuHotPlug: true, RamHotPlug: true}
+
+	tests := []struct {
+		name    string
+		current profitbricks.ServerProperties
+		target  profitbricks.ServerProperties
+		volume  profitbricks.VolumeProperties
+		live    bool
+	}{
+		{"grow with hot-plug", running, profitbricks.ServerProperties{Cores: 4, Ram: 4096}, hotPlug, true},
+		{"grow without hot-plug", running, profitbricks.ServerProperties{Cores: 4, Ram: 2048}, profitbricks.VolumeProperties{}, false},
+		{"shrink without hot-unplug", running, profitbricks.ServerProperties{Cores: 2, Ram: 1024}, hotPlug, false},
+		{"shrink with hot-unplug", running, profitbricks.ServerProperties{Cores: 1, Ram: 2048}, profitbricks.VolumeProperties{CpuHotUnplug: true}, true},
+		{"cpu family change", running, profitbricks.ServerProperties{Cores: 2, Ram: 2048, CpuFamily: "INTEL_XEON"}, hotPlug, false},
+		{"stopped server", profitbricks.ServerProperties{Cores: 2, Ram: 2048, VmState: "SHUTOFF"}, profitbricks.ServerProperties{Cores: 4, Ram: 2048}, hotPlug, false},
+	}
+
+	for _, test := range tests {
+		if live := canResizeLive(test.current, test.target, test.volume); live != test.live {
+			t.Errorf("%s: expected live %t, got %t", test.name, test.live, live)
+		}
+	}
+}
+
+func TestCheckResizeLimits(t *testing.T) {
+	limits := &profitbricks.ResourcesLimits{
+		CoresPerServer:   8,
+		CoresPerContract: 10,
+		CoresProvisioned: 8,
+		RamPerServer:     16384,
+		RamPerContract:   32768,
+		RamProvisioned:   16384,
+	}
+	current := profitbricks.ServerProperties{Cores: 2, Ram: 2048}
+
+	tests := []struct {
+		cores, ram int
+		valid      bool
+	}{
+		{4, 4096, true},
+		{9, 2048, false},
+		{2, 20480, false},
+		{5, 2048, false},
+		{1, 1024, true},
+	}
+
+	for _, test := range tests {
+		err := checkResizeLimits(limits, current, test.cores, test.ram)
+		if test.valid != (err == nil) {
+			t.Errorf("%d cores, %d MB: expected valid %t, got %v", test.cores, test.ram, test.valid, err)
+		}
+	}
+
+	if err := checkResizeLimits(nil, current, 64, 65536); err != nil {
+		t.Errorf("Contract without limits: unexpected error %s", err)
+	}
+}
@@ -0,0 +1,99 @@
+package profitbricks
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+// NotFoundError is returned when a resource of the machine no longer exists.
+type NotFoundError struct {
+	Resource string
+	Id       string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s does not exist", e.Resource, e.Id)
+}
+
+// APIError is returned when the API rejects a request.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 401 {
+		return "Unauthorized. Either user name or password are incorrect."
+	}
+	return fmt.Sprintf("ProfitBricks API error %d: %s", e.StatusCode, e.Message)
+}
+
+// UnreachableError is returned when the API cannot be reached or fails on its side.
+type UnreachableError struct {
+	Err error
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("ProfitBricks API is unreachable: %s", e.Err)
+}
+
+// NoIPError is returned when the server has no NIC with an IP address.
+type NoIPError struct {
+	ServerId string
+}
+
+func (e *NoIPError) Error() string {
+	return fmt.Sprintf("Server %s has no NIC with an IP address", e.ServerId)
+}
+
+// getServer fetches the machine's server. The SDK panics when the API cannot be
+// reached, the panic is returned as an UnreachableError.
+func (d *Driver) getServer() (server profitbricks.Server, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &UnreachableError{Err: fmt.Errorf("%v", r)}
+		}
+	}()
+
+	d.setPB()
+	server = profitbricks.GetServer(d.DatacenterId, d.ServerId)
+	switch {
+	case server.StatusCode == 404:
+		return server, &NotFoundError{Resource: "Server", Id: d.ServerId}
+	case server.StatusCode > 499:
+		return server, &UnreachableError{Err: fmt.Errorf("status %d: %s", server.StatusCode, server.Response)}
+	case server.StatusCode > 299:
+		return server, &APIError{StatusCode: server.StatusCode, Message: server.Response}
+	}
+	return server, nil
+}
+
+// machineNic returns the NIC of the machine, selected by its stored ID, then by the
+// machine's LAN, then as the only NIC of the server.
+func (d *Driver) machineNic(server profitbricks.Server) *profitbricks.Nic {
+	if server.Entities == nil || server.Entities.Nics == nil {
+		return nil
+	}
+	nics := server.Entities.Nics.Items
+
+	if d.NicId != "" {
+		for i := range nics {
+			if nics[i].Id == d.NicId {
+				return &nics[i]
+			}
+		}
+	}
+	if lanId, err := strconv.Atoi(d.LanId); err == nil {
+		for i := range nics {
+			if nics[i].Properties != nil && nics[i].Properties.Lan == lanId {
+				return &nics[i]
+			}
+		}
+	}
+	if len(nics) == 1 {
+		return &nics[0]
+	}
+	return nil
+}
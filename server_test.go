@@ -0,0 +1,95 @@
+package profitbricks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/state"
+)
+
+func newAPITestDriver(handler http.HandlerFunc) (*Driver, func()) {
+	server := httptest.NewServer(handler)
+	d := &Driver{
+		BaseDriver:   &drivers.BaseDriver{MachineName: "test"},
+		URL:          server.URL,
+		DatacenterId: "dc",
+		ServerId:     "srv",
+	}
+	return d, server.Close
+}
+
+func TestGetIP(t *testing.T) {
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"srv","entities":{"nics":{"items":[
+			{"id":"nic-1","properties":{"lan":1,"ips":["10.0.0.1"]}},
+			{"id":"nic-2","properties":{"lan":2,"ips":["203.0.113.7"]}}]}}}`))
+	})
+	defer done()
+
+	d.LanId = "2"
+	ip, err := d.GetIP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip != "203.0.113.7" || d.NicId != "nic-2" {
+		t.Errorf("Expected the NIC of LAN 2, got %s on %s", ip, d.NicId)
+	}
+
+	d.LanId = ""
+	d.NicId = "nic-1"
+	if ip, _ := d.GetIP(); ip != "10.0.0.1" {
+		t.Errorf("Expected the stored NIC, got %s", ip)
+	}
+
+	d.NicId = ""
+	if _, err := d.GetIP(); err == nil {
+		t.Error("Expected an error when the NIC cannot be told apart")
+	} else if _, ok := err.(*NoIPError); !ok {
+		t.Errorf("Expected a NoIPError, got %T: %s", err, err)
+	}
+}
+
+func TestGetIPErrors(t *testing.T) {
+	status := http.StatusNotFound
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+	defer done()
+
+	if _, err := d.GetIP(); err == nil {
+		t.Error("Expected an error for a deleted server")
+	} else if _, ok := err.(*NotFoundError); !ok {
+		t.Errorf("Expected a NotFoundError, got %T: %s", err, err)
+	}
+
+	status = http.StatusServiceUnavailable
+	d.IPAddress = "203.0.113.7"
+	if ip, err := d.GetIP(); err != nil || ip != "203.0.113.7" {
+		t.Errorf("Expected the cached IP address, got %s, %v", ip, err)
+	}
+
+	d.URL = "http://127.0.0.1:1"
+	d.IPAddress = ""
+	if _, err := d.GetIP(); err == nil {
+		t.Error("Expected an error for an unreachable API")
+	} else if _, ok := err.(*UnreachableError); !ok {
+		t.Errorf("Expected an UnreachableError, got %T: %s", err, err)
+	}
+}
+
+func TestGetStateNotFound(t *testing.T) {
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer done()
+
+	st, err := d.GetState()
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Errorf("Expected a NotFoundError, got %T: %v", err, err)
+	}
+	if st != state.Error {
+		t.Errorf("Expected state %s, got %s", state.Error, st)
+	}
+}
@@ -0,0 +1,52 @@
+package profitbricks
+
+import (
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const defaultStopGracePeriod = 60
+
+// runSSHCommand runs a script on the machine, tests replace it.
+var runSSHCommand = drivers.RunSSHCommandFromDriver
+
+// sudoPreamble starts the scripts run on the machine over SSH. It stops them at the
+// first failing command and sets $SUDO to run privileged commands as a non-root user.
+const sudoPreamble = `set -e
+SUDO=""
+[ "$(id -u)" -eq 0 ] || SUDO="sudo -n"
+`
+
+// shutdownCommand powers the guest off in the background, so the SSH command returns
+// before the connection drops. Checking sudo first makes a missing privilege fail the
+// command instead of the background job.
+const shutdownCommand = sudoPreamble + `$SUDO true
+nohup sh -c "sleep 1; $SUDO poweroff" >/dev/null 2>&1 &`
+
+// shutdown stops the server cleanly through the guest OS, then stops it through the
+// API so it is deallocated. When the guest cannot be reached or does not power off
+// within the grace period, the server is only stopped through the API.
+func (d *Driver) shutdown() error {
+	if d.StopGracePeriod > 0 {
+		err := d.shutdownGuest(time.Duration(d.StopGracePeriod) * time.Second)
+		if err != nil {
+			log.Warnf("Clean shutdown failed, stopping the server through the API: %s", err)
+		}
+	}
+	return d.powerOff()
+}
+
+func (d *Driver) shutdownGuest(gracePeriod time.Duration) error {
+	log.Info("Shutting down the guest OS")
+	if _, err := runSSHCommand(d, shutdownCommand); err != nil {
+		return err
+	}
+
+	attempts := int(gracePeriod / stateWaitInterval)
+	if attempts < 1 {
+		attempts = 1
+	}
+	return d.waitForVmStateWithin("SHUTOFF", attempts)
+}
@@ -0,0 +1,60 @@
+package profitbricks
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+func TestShutdownStopsServer(t *testing.T) {
+	defer func(run func(drivers.Driver, string) (string, error)) { runSSHCommand = run }(runSSHCommand)
+
+	tests := []struct {
+		name        string
+		gracePeriod int
+		sshErr      error
+		ssh         bool
+	}{
+		{"clean shutdown", 5, nil, true},
+		{"guest unreachable", 5, errors.New("connection refused"), true},
+		{"no grace period", 0, nil, false},
+	}
+
+	for _, test := range tests {
+		stops := 0
+		d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "POST" && r.URL.Path == "/datacenters/dc/servers/srv/stop":
+				stops++
+				w.Header().Set("Location", "http://"+r.Host+"/requests/1/status")
+				w.WriteHeader(http.StatusAccepted)
+			case r.URL.Path == "/requests/1/status":
+				w.Write([]byte(`{"metadata":{"status":"DONE"}}`))
+			case r.URL.Path == "/datacenters/dc/servers/srv":
+				w.Write([]byte(`{"id":"srv","properties":{"vmState":"SHUTOFF"}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+
+		ssh := false
+		runSSHCommand = func(drivers.Driver, string) (string, error) {
+			ssh = true
+			return "", test.sshErr
+		}
+		d.StopGracePeriod = test.gracePeriod
+
+		if err := d.shutdown(); err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+		}
+		if stops != 1 {
+			t.Errorf("%s: expected the server to be stopped through the API once, got %d", test.name, stops)
+		}
+		if ssh != test.ssh {
+			t.Errorf("%s: expected a guest shutdown %t, got %t", test.name, test.ssh, ssh)
+		}
+		done()
+	}
+}
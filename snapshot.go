@@ -0,0 +1,178 @@
+package profitbricks
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+const snapshotTimeFormat = "20060102-150405"
+
+// MachineSnapshot is a snapshot of a machine's boot volume.
+type MachineSnapshot struct {
+	Id       string
+	Name     string
+	Location string
+	Size     int
+	Created  time.Time
+}
+
+func newMachineSnapshot(snapshot profitbricks.Snapshot, created time.Time) MachineSnapshot {
+	return MachineSnapshot{
+		Id:       snapshot.Id,
+		Name:     snapshot.Properties.Name,
+		Location: snapshot.Properties.Location,
+		Size:     snapshot.Properties.Size,
+		Created:  created,
+	}
+}
+
+func (d *Driver) snapshotName(t time.Time) string {
+	return d.MachineName + "-" + t.UTC().Format(snapshotTimeFormat)
+}
+
+// snapshotTime parses the creation time out of the name of one of the machine's
+// snapshots.
+func (d *Driver) snapshotTime(name string) (time.Time, bool) {
+	prefix := d.MachineName + "-"
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(snapshotTimeFormat, strings.TrimPrefix(name, prefix))
+	return t, err == nil
+}
+
+// CreateSnapshot snapshots the boot volume, naming the snapshot after the machine
+// and the current time.
+func (d *Driver) CreateSnapshot() (*MachineSnapshot, error) {
+	now := time.Now()
+	snapshot, err := d.snapshotBootVolume(d.snapshotName(now), "docker-machine snapshot of "+d.MachineName)
+	if err != nil {
+		return nil, err
+	}
+	result := newMachineSnapshot(snapshot, now.UTC().Truncate(time.Second))
+	return &result, nil
+}
+
+func (d *Driver) snapshotBootVolume(name, description string) (profitbricks.Snapshot, error) {
+	server, err := d.getServer()
+	if err != nil {
+		return profitbricks.Snapshot{}, err
+	}
+	volume := bootVolume(server)
+	if volume == nil {
+		return profitbricks.Snapshot{}, errors.New("Server has no boot volume")
+	}
+
+	log.Infof("Creating snapshot %s", name)
+	snapshot := profitbricks.CreateSnapshot(d.DatacenterId, volume.Id, name, description)
+	if snapshot.StatusCode > 299 {
+		return profitbricks.Snapshot{}, errors.New("Error while creating a snapshot " + snapshot.Response)
+	}
+	if err := d.waitTillProvisioned(snapshot.Headers.Get("Location")); err != nil {
+		return profitbricks.Snapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// ListSnapshots returns the snapshots of the machine in its location, oldest first.
+func (d *Driver) ListSnapshots() ([]MachineSnapshot, error) {
+	d.setPB()
+	snapshots := profitbricks.ListSnapshots()
+	if snapshots.StatusCode > 299 {
+		return nil, fmt.Errorf("Error occurred while listing snapshots: %s", snapshots.Response)
+	}
+
+	var result []MachineSnapshot
+	for _, snapshot := range snapshots.Items {
+		if snapshot.Properties.Location != d.Location {
+			continue
+		}
+		if created, ok := d.snapshotTime(snapshot.Properties.Name); ok {
+			result = append(result, newMachineSnapshot(snapshot, created))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+	return result, nil
+}
+
+// RestoreSnapshot restores one of the machine's snapshots, by name or ID, onto the
+// boot volume. The server is stopped during the restore and started again afterwards.
+func (d *Driver) RestoreSnapshot(snapshot string) error {
+	snapshots, err := d.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	var target *MachineSnapshot
+	for i := range snapshots {
+		if snapshots[i].Id == snapshot || snapshots[i].Name == snapshot {
+			target = &snapshots[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("Snapshot %s does not belong to machine %s", snapshot, d.MachineName)
+	}
+
+	server, err := d.getServer()
+	if err != nil {
+		return err
+	}
+	volume := bootVolume(server)
+	if volume == nil {
+		return errors.New("Server has no boot volume")
+	}
+
+	if server.Properties.VmState != "SHUTOFF" {
+		log.Info("Stopping the server")
+		if err := d.shutdown(); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Restoring snapshot %s", target.Name)
+	resp := profitbricks.RestoreSnapshot(d.DatacenterId, volume.Id, target.Id)
+	if resp.StatusCode > 299 {
+		return errors.New(string(resp.Body))
+	}
+	if err := d.waitTillProvisioned(resp.Headers.Get("Location")); err != nil {
+		return err
+	}
+
+	log.Info("Starting the server")
+	return d.powerOn()
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots of the machine and returns
+// the deleted ones.
+func (d *Driver) PruneSnapshots(keep int) ([]MachineSnapshot, error) {
+	if keep < 0 {
+		return nil, fmt.Errorf("Cannot keep %d snapshots", keep)
+	}
+	snapshots, err := d.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) <= keep {
+		return nil, nil
+	}
+
+	prune := snapshots[:len(snapshots)-keep]
+	for i, snapshot := range prune {
+		log.Infof("Deleting snapshot %s", snapshot.Name)
+		resp := profitbricks.DeleteSnapshot(snapshot.Id)
+		if resp.StatusCode > 299 {
+			return prune[:i], errors.New(string(resp.Body))
+		}
+		if err := d.waitTillProvisioned(resp.Headers.Get("Location")); err != nil {
+			return prune[:i], err
+		}
+	}
+	return prune, nil
+}
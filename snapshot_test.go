@@ -0,0 +1,83 @@
+package profitbricks
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotName(t *testing.T) {
+	d := &Driver{}
+	d.BaseDriver = NewDriver("build-1", "").(*Driver).BaseDriver
+
+	created := time.Date(2017, 8, 1, 13, 52, 49, 0, time.UTC)
+	name := d.snapshotName(created)
+	if name != "build-1-20170801-135249" {
+		t.Fatalf("Unexpected snapshot name %s", name)
+	}
+
+	parsed, ok := d.snapshotTime(name)
+	if !ok || !parsed.Equal(created) {
+		t.Errorf("Snapshot time was not parsed from %s: %s", name, parsed)
+	}
+
+	for _, other := range []string{"build-10-20170801-135249", "build-1-latest", "build-20170801-135249"} {
+		if _, ok := d.snapshotTime(other); ok {
+			t.Errorf("Snapshot %s should not belong to machine %s", other, d.MachineName)
+		}
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	var deleted []string
+	d, done := newAPITestDriver(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE":
+			deleted = append(deleted, r.URL.Path)
+			w.Header().Set("Location", "http://"+r.Host+"/requests/1/status")
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/requests/1/status":
+			w.Write([]byte(`{"metadata":{"status":"DONE"}}`))
+		case r.URL.Path == "/snapshots":
+			w.Write([]byte(`{"items":[
+				{"id":"s1","properties":{"name":"test-20170801-000000","location":"us/las"}},
+				{"id":"s3","properties":{"name":"test-20170901-000000","location":"us/las"}},
+				{"id":"s0","properties":{"name":"test-20170715-000000","location":"us/las"}},
+				{"id":"s4","properties":{"name":"test-20171001-000000","location":"us/las"}},
+				{"id":"other-machine","properties":{"name":"test-1-20170101-000000","location":"us/las"}},
+				{"id":"golden","properties":{"name":"test-golden-20170101-000000","location":"us/las"}},
+				{"id":"clone","properties":{"name":"test-clone-0-20170101-000000","location":"us/las"}},
+				{"id":"other-location","properties":{"name":"test-20170101-000000","location":"de/fra"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer done()
+	d.Location = "us/las"
+
+	pruned, err := d.PruneSnapshots(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/snapshots/s0", "/snapshots/s1"}
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("Expected %v to be deleted, deleted %v", expected, deleted)
+	}
+	if len(pruned) != 2 || pruned[0].Id != "s0" || pruned[1].Id != "s1" {
+		t.Errorf("Expected the pruned snapshots s0 and s1, got %+v", pruned)
+	}
+
+	deleted = nil
+	if pruned, err := d.PruneSnapshots(4); err != nil || len(pruned) > 0 || len(deleted) > 0 {
+		t.Errorf("Expected nothing to be pruned when keeping 4, pruned %v, deleted %v, error %v", pruned, deleted, err)
+	}
+
+	if _, err := d.PruneSnapshots(0); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"/snapshots/s0", "/snapshots/s1", "/snapshots/s3", "/snapshots/s4"}
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("Expected %v to be deleted, deleted %v", expected, deleted)
+	}
+}
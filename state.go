@@ -0,0 +1,67 @@
+package profitbricks
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/state"
+)
+
+// machineState maps the VM state of a server to a machine state. The metadata state is
+// the provisioning state of the server resource, BUSY while a request changes it, which
+// tells a starting or stopping VM apart. BUSY with a running VM is only a guess: the API
+// reports the same for a live resize, a CD-ROM attach or a NIC change, so callers must
+// not rely on Stopping meaning a stop was requested.
+func machineState(vmState, metadataState string) state.State {
+	switch metadataState {
+	case "BUSY":
+		switch vmState {
+		case "RUNNING":
+			return state.Stopping
+		case "SHUTOFF", "SHUTDOWN", "NOSTATE", "":
+			return state.Starting
+		}
+	case "INACTIVE":
+		return state.Stopped
+	}
+
+	switch vmState {
+	case "RUNNING":
+		return state.Running
+	case "BLOCKED":
+		// blocked on a resource, the VM is still up
+		return state.Running
+	case "PAUSED":
+		return state.Paused
+	case "SHUTDOWN", "SHUTOFF":
+		return state.Stopped
+	case "CRASHED":
+		return state.Error
+	}
+	return state.None
+}
+
+// OperationError is returned when starting, stopping, restarting or killing a machine
+// was rejected by the API, its request failed, or the server did not reach the expected
+// state.
+type OperationError struct {
+	Operation string
+	Err       error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("Error during %s of the server: %s", e.Operation, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// StateTimeoutError is returned when the server does not reach a VM state in time.
+type StateTimeoutError struct {
+	Expected string
+	Current  string
+}
+
+func (e *StateTimeoutError) Error() string {
+	return fmt.Sprintf("Server did not reach state %s in time, it is %s", e.Expected, e.Current)
+}
@@ -0,0 +1,59 @@
+package profitbricks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/libmachine/state"
+)
+
+func TestMachineState(t *testing.T) {
+	tests := []struct {
+		vmState  string
+		metadata string
+		expected state.State
+	}{
+		{"RUNNING", "AVAILABLE", state.Running},
+		{"RUNNING", "BUSY", state.Stopping},
+		{"RUNNING", "INACTIVE", state.Stopped},
+		{"SHUTOFF", "AVAILABLE", state.Stopped},
+		{"SHUTOFF", "BUSY", state.Starting},
+		{"SHUTOFF", "INACTIVE", state.Stopped},
+		{"SHUTDOWN", "AVAILABLE", state.Stopped},
+		{"SHUTDOWN", "BUSY", state.Starting},
+		{"SHUTDOWN", "INACTIVE", state.Stopped},
+		{"CRASHED", "AVAILABLE", state.Error},
+		{"CRASHED", "BUSY", state.Error},
+		{"CRASHED", "INACTIVE", state.Stopped},
+		{"PAUSED", "AVAILABLE", state.Paused},
+		{"PAUSED", "BUSY", state.Paused},
+		{"PAUSED", "INACTIVE", state.Stopped},
+		{"BLOCKED", "AVAILABLE", state.Running},
+		{"BLOCKED", "BUSY", state.Running},
+		{"BLOCKED", "INACTIVE", state.Stopped},
+		{"NOSTATE", "AVAILABLE", state.None},
+		{"NOSTATE", "BUSY", state.Starting},
+		{"NOSTATE", "INACTIVE", state.Stopped},
+		{"", "BUSY", state.Starting},
+		{"", "", state.None},
+		{"UNKNOWN", "AVAILABLE", state.None},
+	}
+
+	for _, test := range tests {
+		if s := machineState(test.vmState, test.metadata); s != test.expected {
+			t.Errorf("vmState %s, metadata %s: expected %s, got %s", test.vmState, test.metadata, test.expected, s)
+		}
+	}
+}
+
+func TestOperationError(t *testing.T) {
+	err := error(&OperationError{Operation: "stop", Err: &StateTimeoutError{Expected: "SHUTOFF", Current: "RUNNING"}})
+
+	var timeout *StateTimeoutError
+	if !errors.As(err, &timeout) || timeout.Current != "RUNNING" {
+		t.Errorf("Expected the state timeout to be wrapped, got %v", err)
+	}
+	if err.Error() != "Error during stop of the server: Server did not reach state SHUTOFF in time, it is RUNNING" {
+		t.Errorf("Unexpected message %q", err)
+	}
+}
@@ -0,0 +1,132 @@
+package profitbricks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+// DefaultStorePath returns the docker-machine store, honouring $MACHINE_STORAGE_PATH.
+func DefaultStorePath() string {
+	if storePath := os.Getenv("MACHINE_STORAGE_PATH"); storePath != "" {
+		return storePath
+	}
+	return filepath.Join(mcnutils.GetHomeDir(), ".docker", "machine")
+}
+
+func machineConfigPath(storePath, name string) string {
+	return filepath.Join(storePath, "machines", name, "config.json")
+}
+
+// LoadDriver reads the driver of an existing machine from the docker-machine store.
+func LoadDriver(storePath, name string) (*Driver, error) {
+	host, err := readHostConfig(storePath, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var driverName string
+	if err := json.Unmarshal(host["DriverName"], &driverName); err != nil {
+		return nil, fmt.Errorf("Error reading the config of machine %s: %s", name, err)
+	}
+	d := NewDriver(name, storePath).(*Driver)
+	if driverName != d.DriverName() {
+		return nil, fmt.Errorf("Machine %s uses the %s driver, not %s", name, driverName, d.DriverName())
+	}
+
+	if err := json.Unmarshal(host["Driver"], d); err != nil {
+		return nil, fmt.Errorf("Error reading the driver config of machine %s: %s", name, err)
+	}
+	if d.BaseDriver == nil {
+		d.BaseDriver = &drivers.BaseDriver{MachineName: name, StorePath: storePath}
+	}
+	return d, nil
+}
+
+// SaveConfig writes the driver back to the machine's config.json, keeping the rest of
+// the host config untouched.
+func (d *Driver) SaveConfig() error {
+	host, err := readHostConfig(d.StorePath, d.MachineName)
+	if err != nil {
+		return err
+	}
+
+	driverJSON, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	host["Driver"] = driverJSON
+
+	data, err := json.MarshalIndent(host, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	path := machineConfigPath(d.StorePath, d.MachineName)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadDrivers loads all machines of the docker-machine store that use this driver.
+func LoadDrivers(storePath string) ([]*Driver, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(storePath, "machines"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := NewDriver("", storePath).DriverName()
+	var result []*Driver
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		host, err := readHostConfig(storePath, entry.Name())
+		if err != nil {
+			log.Debugf("Skipping machine %s: %s", entry.Name(), err)
+			continue
+		}
+		var driverName string
+		if json.Unmarshal(host["DriverName"], &driverName) != nil || driverName != name {
+			continue
+		}
+		d, err := LoadDriver(storePath, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+func readHostConfig(storePath, name string) (map[string]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(machineConfigPath(storePath, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("Machine %s does not exist in %s", name, storePath)
+		}
+		return nil, err
+	}
+
+	var host map[string]json.RawMessage
+	if err := json.Unmarshal(data, &host); err != nil {
+		return nil, fmt.Errorf("Error reading the config of machine %s: %s", name, err)
+	}
+	return host, nil
+}
+
+// RemoveFromStore deletes the machine's directory from the docker-machine store.
+func (d *Driver) RemoveFromStore() error {
+	return os.RemoveAll(filepath.Dir(machineConfigPath(d.StorePath, d.MachineName)))
+}
@@ -0,0 +1,60 @@
+package profitbricks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAndSaveDriver(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "machine-store-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+
+	machineDir := filepath.Join(storePath, "machines", "test")
+	if err := os.MkdirAll(machineDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"ConfigVersion":3,"DriverName":"profitbricks","Name":"test","HostOptions":{"Driver":""},
+		"Driver":{"MachineName":"test","StorePath":"` + storePath + `","ServerId":"srv","Cores":2,"Ram":2048}}`
+	if err := ioutil.WriteFile(filepath.Join(machineDir, "config.json"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := LoadDriver(storePath, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ServerId != "srv" || d.Cores != 2 || d.MachineName != "test" {
+		t.Fatalf("Driver was not loaded: %+v", d)
+	}
+
+	d.Cores = 4
+	if err := d.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(machineDir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var host struct {
+		Name        string
+		HostOptions map[string]interface{}
+		Driver      Driver
+	}
+	if err := json.Unmarshal(data, &host); err != nil {
+		t.Fatal(err)
+	}
+	if host.Driver.Cores != 4 || host.Name != "test" || host.HostOptions == nil {
+		t.Errorf("Host config was not saved correctly: %s", data)
+	}
+
+	if _, err := LoadDriver(storePath, "missing"); err == nil {
+		t.Error("Expected an error for a missing machine")
+	}
+}
@@ -0,0 +1,94 @@
+package profitbricks
+
+import (
+	"sort"
+	"strings"
+)
+
+const maxSuggestions = 3
+
+// didYouMean returns a " did you mean ...?" suffix naming the candidates closest to
+// value, or "" when none is close.
+func didYouMean(value string, candidates []string) string {
+	closest := closestMatches(value, candidates, maxSuggestions)
+	if len(closest) == 0 {
+		return ""
+	}
+	return ", did you mean " + strings.Join(closest, ", ") + "?"
+}
+
+// closestMatches returns up to n candidates within a third of value's length in edit
+// distance, ignoring case, closest first. A candidate's prefix of value's length also
+// counts, so long image names are suggested for their beginning.
+func closestMatches(value string, candidates []string, n int) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+
+	value = strings.ToLower(value)
+	threshold := len(value) / 3
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	seen := map[string]bool{}
+	var matches []match
+	for _, candidate := range candidates {
+		if candidate == "" || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+
+		lower := strings.ToLower(candidate)
+		distance := levenshtein(value, lower)
+		if len(lower) > len(value) {
+			if prefix := levenshtein(value, lower[:len(value)]); prefix < distance {
+				distance = prefix
+			}
+		}
+		if distance <= threshold {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+	var names []string
+	for i := 0; i < len(matches) && i < n; i++ {
+		names = append(names, matches[i].name)
+	}
+	return names
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,42 @@
+package profitbricks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"us/las", "us/las", 0},
+		{"us/lass", "us/las", 1},
+		{"INTEL_XEN", "INTEL_XEON", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if d := levenshtein(test.a, test.b); d != test.distance {
+			t.Errorf("levenshtein(%q, %q) = %d, expected %d", test.a, test.b, d, test.distance)
+		}
+	}
+}
+
+func TestClosestMatches(t *testing.T) {
+	candidates := []string{"ubuntu:latest", "Ubuntu-18.04-LTS-server-2018-06-01", "CentOS-7-server-2018-06-01", "debian:latest"}
+
+	if matches := closestMatches("ubuntu:latst", candidates, 3); !reflect.DeepEqual(matches, []string{"ubuntu:latest"}) {
+		t.Errorf("Unexpected matches %v", matches)
+	}
+	if matches := closestMatches("Ubunto-18.04", candidates, 3); !reflect.DeepEqual(matches, []string{"Ubuntu-18.04-LTS-server-2018-06-01"}) {
+		t.Errorf("Unexpected matches %v", matches)
+	}
+	if matches := closestMatches("windows", candidates, 3); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %v", matches)
+	}
+	if suffix := didYouMean("us/lass", []string{"us/las", "us/ewr", "de/fra"}); suffix != ", did you mean us/las?" {
+		t.Errorf("Unexpected suggestion %q", suffix)
+	}
+}
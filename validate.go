@@ -0,0 +1,135 @@
+package profitbricks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	diskTypes               = []string{"HDD", "SSD"}
+	serverAvailabilityZones = []string{"AUTO", "ZONE_1", "ZONE_2"}
+	volumeAvailabilityZones = []string{"AUTO", "ZONE_1", "ZONE_2", "ZONE_3"}
+)
+
+// checkAvailabilityZones validates the server and volume availability zones.
+func (d *Driver) checkAvailabilityZones() error {
+	if d.ServerAvailabilityZone != "" && !contains(serverAvailabilityZones, d.ServerAvailabilityZone) {
+		return fmt.Errorf("Server availability zone %s is not valid, use one of %s%s", d.ServerAvailabilityZone,
+			strings.Join(serverAvailabilityZones, ", "), didYouMean(d.ServerAvailabilityZone, serverAvailabilityZones))
+	}
+	if d.VolumeAvailabilityZone != "" && !contains(volumeAvailabilityZones, d.VolumeAvailabilityZone) {
+		return fmt.Errorf("Volume availability zone %s is not valid, use one of %s%s", d.VolumeAvailabilityZone,
+			strings.Join(volumeAvailabilityZones, ", "), didYouMean(d.VolumeAvailabilityZone, volumeAvailabilityZones))
+	}
+	return nil
+}
+
+// checkLocation validates the location and the CPU family available in it.
+func (d *Driver) checkLocation() error {
+	locations, err := d.listLocations()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(locations))
+	for i, location := range locations {
+		ids[i] = location.Id
+		if location.Id != d.Location {
+			continue
+		}
+		families := CpuFamilies(location)
+		if len(families) == 0 {
+			log.Debugf("Location %s lists no CPU families, skipping the CPU family check", d.Location)
+		} else if d.CpuFamily != "" && !contains(families, d.CpuFamily) {
+			return fmt.Errorf("CPU family %s is not available in %s, use one of %s%s", d.CpuFamily, d.Location,
+				strings.Join(families, ", "), didYouMean(d.CpuFamily, families))
+		}
+		return nil
+	}
+	return fmt.Errorf("Location %s does not exist%s", d.Location, didYouMean(d.Location, ids))
+}
+
+// checkOptions validates the create options that can be checked without the API.
+func (d *Driver) checkOptions() []error {
+	var errs []error
+	if d.Username == "" {
+		errs = append(errs, errors.New("Please provide username as parameter --profitbricks-username or as environment variable $PROFITBRICKS_USERNAME"))
+	}
+	if d.Password == "" {
+		errs = append(errs, errors.New("Please provide password as parameter --profitbricks-password or as environment variable $PROFITBRICKS_PASSWORD"))
+	}
+
+	if d.Cores < 1 {
+		errs = append(errs, fmt.Errorf("Cores must be at least 1, got %d", d.Cores))
+	}
+	if d.Ram < 256 || d.Ram%256 != 0 {
+		errs = append(errs, fmt.Errorf("RAM must be a multiple of 256 MB, got %d MB", d.Ram))
+	}
+	if d.DiskSize < 1 {
+		errs = append(errs, fmt.Errorf("Disk size must be at least 1 GB, got %d GB", d.DiskSize))
+	}
+	if !contains(diskTypes, d.DiskType) {
+		errs = append(errs, fmt.Errorf("Disk type %s is not valid, use one of %s%s", d.DiskType,
+			strings.Join(diskTypes, ", "), didYouMean(d.DiskType, diskTypes)))
+	}
+	if err := d.checkAvailabilityZones(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if d.ImageType != "" && !contains(imageTypes, d.ImageType) {
+		errs = append(errs, fmt.Errorf("Image type %s is not valid, use one of %s", d.ImageType, strings.Join(imageTypes, ", ")))
+	}
+	if d.ImageType == "CDROM" && !d.BootCdrom {
+		errs = append(errs, errors.New("Image type CDROM requires --profitbricks-boot-cdrom"))
+	}
+
+	if d.ImagePassword != "" {
+		if d.GenerateImagePassword {
+			errs = append(errs, errors.New("Either provide an image password or have one generated, not both"))
+		}
+		if err := validateImagePassword(d.ImagePassword); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if d.PrivateKeyPath != "" {
+		if _, err := os.Stat(d.PrivateKeyPath + ".pub"); err != nil {
+			errs = append(errs, fmt.Errorf("Public key of %s not found: %s", d.PrivateKeyPath, err))
+		}
+	}
+	return errs
+}
+
+// checkImage validates the location, CPU family and image, and the boot volume options
+// against the image.
+func (d *Driver) checkImage() []error {
+	var errs []error
+	if err := d.checkLocation(); err != nil {
+		errs = append(errs, err)
+	}
+
+	d.ImageId, d.ImageName = "", ""
+	if _, err := d.findImage(d.Image); err != nil {
+		errs = append(errs, err)
+	}
+	if err := d.checkVolumeOptions(d.image); err != nil {
+		errs = append(errs, err)
+	}
+
+	if d.BootCdrom && (d.ImagePassword != "" || d.GenerateImagePassword) {
+		errs = append(errs, fmt.Errorf("CD-ROM image %s does not accept an image password", d.Image))
+	}
+	if d.BootCdrom && d.PrivateKeyPath == "" {
+		errs = append(errs, fmt.Errorf("CD-ROM image %s does not accept SSH keys, provide a private key authorized on it with --profitbricks-ssh-key-path", d.Image))
+	}
+	if d.UseSnapshot && (d.ImagePassword != "" || d.GenerateImagePassword) {
+		errs = append(errs, fmt.Errorf("Snapshot %s does not accept an image password", d.Image))
+	}
+	if d.UseSnapshot && d.PrivateKeyPath == "" {
+		errs = append(errs, fmt.Errorf("Snapshot %s does not accept SSH keys, provide a private key authorized on it with --profitbricks-ssh-key-path", d.Image))
+	}
+	return errs
+}
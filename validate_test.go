@@ -0,0 +1,42 @@
+package profitbricks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckAvailabilityZones(t *testing.T) {
+	if err := (&Driver{ServerAvailabilityZone: "AUTO", VolumeAvailabilityZone: "ZONE_3"}).checkAvailabilityZones(); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+
+	err := (&Driver{ServerAvailabilityZone: "ZONE_3"}).checkAvailabilityZones()
+	if err == nil {
+		t.Fatal("Expected an error for server zone ZONE_3")
+	}
+
+	err = (&Driver{VolumeAvailabilityZone: "ZONE1"}).checkAvailabilityZones()
+	if err == nil || !strings.Contains(err.Error(), "did you mean ZONE_1") {
+		t.Errorf("Expected a suggestion, got %v", err)
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	valid := Driver{
+		Username: "user", Password: "secret",
+		Cores: 2, Ram: 2048, DiskSize: 50, DiskType: "SSD",
+		ServerAvailabilityZone: "AUTO", VolumeAvailabilityZone: "AUTO", ImageType: "HDD",
+	}
+	if errs := valid.checkOptions(); len(errs) != 0 {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+
+	invalid := Driver{
+		Cores: 0, Ram: 1000, DiskSize: 0, DiskType: "NVME",
+		ServerAvailabilityZone: "ZONE_9", ImageType: "CDROM",
+		ImagePassword: "short", GenerateImagePassword: true,
+	}
+	if errs := invalid.checkOptions(); len(errs) != 10 {
+		t.Errorf("Expected every problem to be reported, got %d: %v", len(errs), errs)
+	}
+}
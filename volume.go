@@ -0,0 +1,151 @@
+package profitbricks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+var (
+	volumeBuses  = []string{"VIRTIO", "IDE"}
+	licenceTypes = []string{"LINUX", "WINDOWS", "WINDOWS2016", "UNKNOWN", "OTHER"}
+)
+
+// hotPlugCapability maps a capability name to the matching image and volume properties.
+type hotPlugCapability struct {
+	image  func(p profitbricks.ImageProperties) bool
+	volume func(p *profitbricks.VolumeProperties) *bool
+}
+
+var hotPlugCapabilities = map[string]hotPlugCapability{
+	"cpuHotPlug": {
+		func(p profitbricks.ImageProperties) bool { return p.CpuHotPlug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.CpuHotPlug },
+	},
+	"cpuHotUnplug": {
+		func(p profitbricks.ImageProperties) bool { return p.CpuHotUnplug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.CpuHotUnplug },
+	},
+	"ramHotPlug": {
+		func(p profitbricks.ImageProperties) bool { return p.RamHotPlug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.RamHotPlug },
+	},
+	"ramHotUnplug": {
+		func(p profitbricks.ImageProperties) bool { return p.RamHotUnplug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.RamHotUnplug },
+	},
+	"nicHotPlug": {
+		func(p profitbricks.ImageProperties) bool { return p.NicHotPlug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.NicHotPlug },
+	},
+	"nicHotUnplug": {
+		func(p profitbricks.ImageProperties) bool { return p.NicHotUnplug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.NicHotUnplug },
+	},
+	"discVirtioHotPlug": {
+		func(p profitbricks.ImageProperties) bool { return p.DiscVirtioHotPlug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.DiscVirtioHotPlug },
+	},
+	"discVirtioHotUnplug": {
+		func(p profitbricks.ImageProperties) bool { return p.DiscVirtioHotUnplug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.DiscVirtioHotUnplug },
+	},
+	"discScsiHotPlug": {
+		func(p profitbricks.ImageProperties) bool { return p.DiscScsiHotPlug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.DiscScsiHotPlug },
+	},
+	"discScsiHotUnplug": {
+		func(p profitbricks.ImageProperties) bool { return p.DiscScsiHotUnplug },
+		func(p *profitbricks.VolumeProperties) *bool { return &p.DiscScsiHotUnplug },
+	},
+}
+
+// lookupHotPlug finds a capability by name, ignoring case.
+func lookupHotPlug(name string) (string, hotPlugCapability, bool) {
+	for key, capability := range hotPlugCapabilities {
+		if strings.EqualFold(key, strings.TrimSpace(name)) {
+			return key, capability, true
+		}
+	}
+	return "", hotPlugCapability{}, false
+}
+
+// setHotPlug enables the requested hot-plug capabilities on the volume.
+func setHotPlug(props *profitbricks.VolumeProperties, names []string) {
+	for _, name := range names {
+		if _, capability, ok := lookupHotPlug(name); ok {
+			*capability.volume(props) = true
+		}
+	}
+}
+
+// checkVolumeOptions validates the boot volume options, and their compatibility with
+// the image when one was resolved. Image aliases carry no properties and are only
+// checked by the API.
+func (d *Driver) checkVolumeOptions(image *profitbricks.Image) error {
+	if d.VolumeBus != "" && !contains(volumeBuses, d.VolumeBus) {
+		return fmt.Errorf("Volume bus %s is not valid, use one of %s", d.VolumeBus, strings.Join(volumeBuses, ", "))
+	}
+	if d.LicenceType != "" && !contains(licenceTypes, d.LicenceType) {
+		return fmt.Errorf("Licence type %s is not valid, use one of %s", d.LicenceType, strings.Join(licenceTypes, ", "))
+	}
+
+	for _, name := range d.HotPlug {
+		key, _, ok := lookupHotPlug(name)
+		if !ok {
+			return fmt.Errorf("Hot-plug capability %s is not valid", name)
+		}
+		if strings.HasPrefix(key, "discVirtio") && d.VolumeBus == "IDE" {
+			return fmt.Errorf("Hot-plug capability %s requires the VIRTIO volume bus", key)
+		}
+	}
+
+	if image == nil {
+		log.Debugf("Image %s is an alias, skipping volume compatibility checks", d.Image)
+		return nil
+	}
+
+	if image.Properties.ImageType != "CDROM" && image.Properties.Size > 0 && d.DiskSize < image.Properties.Size {
+		return fmt.Errorf("Disk size of %d GB is smaller than the %d GB of image %s", d.DiskSize, image.Properties.Size, image.Properties.Name)
+	}
+
+	if d.LicenceType != "" && image.Properties.LicenceType != "" && d.LicenceType != image.Properties.LicenceType {
+		return fmt.Errorf("Licence type %s does not match licence type %s of image %s", d.LicenceType, image.Properties.LicenceType, image.Properties.Name)
+	}
+
+	for _, name := range d.HotPlug {
+		key, capability, _ := lookupHotPlug(name)
+		if !capability.image(image.Properties) {
+			return fmt.Errorf("Image %s does not support %s", image.Properties.Name, key)
+		}
+	}
+
+	return nil
+}
+
+// bootVolume returns the boot volume of a server fetched with its entities.
+func bootVolume(server profitbricks.Server) *profitbricks.Volume {
+	if server.Entities == nil || server.Entities.Volumes == nil || len(server.Entities.Volumes.Items) == 0 {
+		return nil
+	}
+	volumes := server.Entities.Volumes.Items
+	if server.Properties.BootVolume != nil {
+		for i := range volumes {
+			if volumes[i].Id == server.Properties.BootVolume.Id {
+				return &volumes[i]
+			}
+		}
+	}
+	return &volumes[0]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package profitbricks
+
+import (
+	"testing"
+
+	"github.com/profitbricks/profitbricks-sdk-go"
+)
+
+func TestCheckVolumeOptions(t *testing.T) {
+	image := &profitbricks.Image{
+		Properties: profitbricks.ImageProperties{
+			Name:        "Ubuntu-16.04",
+			LicenceType: "LINUX",
+			CpuHotPlug:  true,
+			RamHotPlug:  true,
+		},
+	}
+
+	tests := []struct {
+		bus     string
+		licence string
+		hotPlug []string
+		image   *profitbricks.Image
+		valid   bool
+	}{
+		{"VIRTIO", "", []string{"cpuHotPlug", "ramhotplug"}, image, true},
+		{"VIRTIO", "LINUX", nil, image, true},
+		{"SATA", "", nil, image, false},
+		{"VIRTIO", "BSD", nil, image, false},
+		{"VIRTIO", "WINDOWS", nil, image, false},
+		{"VIRTIO", "", []string{"nicHotPlug"}, image, false},
+		{"VIRTIO", "", []string{"gpuHotPlug"}, image, false},
+		{"IDE", "", []string{"discVirtioHotPlug"}, nil, false},
+		{"IDE", "WINDOWS", []string{"nicHotPlug"}, nil, true},
+	}
+
+	for _, test := range tests {
+		d := &Driver{VolumeBus: test.bus, LicenceType: test.licence, HotPlug: test.hotPlug}
+		err := d.checkVolumeOptions(test.image)
+		if test.valid && err != nil {
+			t.Errorf("bus %s, licence %s, hot-plug %v: unexpected error %s", test.bus, test.licence, test.hotPlug, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("bus %s, licence %s, hot-plug %v: expected an error", test.bus, test.licence, test.hotPlug)
+		}
+	}
+}
+
+func TestCheckVolumeOptionsDiskSize(t *testing.T) {
+	image := &profitbricks.Image{Properties: profitbricks.ImageProperties{Name: "golden", Size: 20}}
+
+	if err := (&Driver{DiskSize: 10}).checkVolumeOptions(image); err == nil {
+		t.Error("Expected an error for a disk smaller than the image")
+	}
+	if err := (&Driver{DiskSize: 20}).checkVolumeOptions(image); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+}
+
+func TestSetHotPlug(t *testing.T) {
+	var props profitbricks.VolumeProperties
+	setHotPlug(&props, []string{"CPUHOTPLUG", "discScsiHotUnplug"})
+
+	if !props.CpuHotPlug || !props.DiscScsiHotUnplug {
+		t.Errorf("Requested capabilities were not set: %+v", props)
+	}
+	if props.RamHotPlug || props.NicHotPlug {
+		t.Errorf("Unrequested capabilities were set: %+v", props)
+	}
+}